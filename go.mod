@@ -3,6 +3,8 @@ module github.com/e2fyi/minio-web
 go 1.16
 
 require (
+	github.com/BurntSushi/toml v1.2.1
+	github.com/alecthomas/chroma v0.10.0
 	github.com/bluele/gcache v0.0.0-20190301044115-79ae3b2d8680
 	github.com/dustin/go-humanize v1.0.0
 	github.com/go-ini/ini v1.42.0 // indirect
@@ -13,5 +15,6 @@ require (
 	go.uber.org/atomic v1.9.0 // indirect
 	go.uber.org/multierr v1.7.0 // indirect
 	go.uber.org/zap v1.10.0
-	golang.org/x/crypto v0.0.0-20211108221036-ceb1ce70b4fa // indirect
+	golang.org/x/crypto v0.0.0-20211108221036-ceb1ce70b4fa
+	gopkg.in/yaml.v2 v2.4.0
 )
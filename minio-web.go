@@ -3,26 +3,89 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
 	app "github.com/e2fyi/minio-web/pkg/app"
 	ext "github.com/e2fyi/minio-web/pkg/ext"
 )
 
 func main() {
 
+	// mint a signed share url instead of starting the server
+	if len(os.Args) > 1 && os.Args[1] == "share" {
+		share(os.Args[2:])
+		return
+	}
+
 	// create new app and load config
 	app := app.NewApp().LoadConfig()
 	// config backend
 	app.ConfigMinioHelper(app.Config.Minio, app.Config.Ext.BucketName, app.Config.Ext.Prefix)
 	// install default index file extension
 	app.ApplyExtension(ext.DefaultIndexFileExtension(app.Config.Ext.DefaultHTMLs...))
+	// redirect folder urls to a trailing slash if needed
+	app.ApplyExtension(ext.TrailingSlashExtension(app.Helper, app.Config.Ext.TrailingSlash))
 	// install default favicon extension
 	app.ApplyExtension(ext.DefaultFaviconExtension(app.Config.Ext.FavIcon))
 	// return cache if available (1000 objects, max 10 Mb)
 	app.ApplyExtension(ext.CacheRequestsExtension(1000, 1024*1024*10))
 	// list folder if needed
-	app.ApplyExtension(ext.ListFolderExtension(app.Helper, app.Config.Ext.ListFolder, app.Config.Ext.ListFolderObjects))
+	app.ApplyExtension(ext.ListFolderExtension(app.Helper, app.Config.Ext.ListFolder, app.Config.Ext.ListFolderObjects, app.Config.Ext.Listing, app.Config.Ext.BasePath))
+	// redirect large objects to presigned urls if needed
+	app.ApplyExtension(ext.PresignExtension(app.Helper, app.Config.Ext.Presign))
 	// render markdown if needed
-	app.ApplyExtension(ext.RenderMarkdownExtension(app.Config.Ext.MarkdownTemplate))
+	app.ApplyExtension(ext.RenderMarkdownExtension(app.Helper, app.Config.Ext.MarkdownTemplate, app.Config.Ext.Markdown, app.Config.Ext.BasePath, app.Config.Ext.DefaultHTMLs))
+	// render code objects as highlighted HTML if needed
+	app.ApplyExtension(ext.SourceViewerExtension(app.Config.Ext.Source))
+	app.ApplyExtension(ext.DataViewerExtension(app.Config.Ext.DataViewer))
+	// limit the bandwidth of the responses if needed
+	app.ApplyExtension(ext.ThrottleExtension(app.Helper, app.Config.Ext.Throttle))
+	// limit the rate of requests by identity and concurrent downloads if needed
+	app.ApplyExtension(ext.RateLimitExtension(app.Config.Ext.RateLimit, app.Config.Ext.TrustedProxies))
+	// authorize authenticated users if policies are provided
+	app.ApplyExtension(ext.AuthorizationExtension(app.Config.Ext.AuthPolicies))
+	// authenticate requests with htpasswd files if provided
+	app.ApplyExtension(ext.BasicAuthExtension(app.Config.Ext.BasicAuth))
+	// authenticate requests with bearer JWTs or OIDC login if provided
+	app.ApplyExtension(ext.OIDCExtension(app.Config.Ext.OIDC))
+	// verify signed share urls if keys are provided
+	app.ApplyExtension(ext.ShareLinksExtension(app.Config.Ext.Share))
+	// limit the rate of requests by client ip or url prefix (or of failed
+	// authentications by client ip) before authenticating them if needed
+	app.ApplyExtension(ext.ClientRateLimitExtension(app.Config.Ext.RateLimit, app.Config.Ext.TrustedProxies))
+	// allow or deny client ips if needed
+	app.ApplyExtension(ext.AccessExtension(app.Config.Ext.Access, app.Config.Ext.TrustedProxies))
+	// handle cross-origin requests if enabled
+	app.ApplyExtension(ext.CorsExtension(app.Helper, app.Config.Ext.Cors))
 	// start server
 	app.StartServer(app.Config.Server)
 }
+
+// share prints a signed share url, i.e.
+// minio-web share [-expires 24h] [-prefix] [-base https://minio-web] <path>
+func share(args []string) {
+	flags := flag.NewFlagSet("share", flag.ExitOnError)
+	expires := flags.Duration("expires", 24*time.Hour, "duration before the url expires")
+	prefix := flags.Bool("prefix", false, "grant access to any url under the path")
+	base := flags.String("base", "", "base url of minio-web (e.g. https://minio-web)")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: minio-web share [-expires 24h] [-prefix] [-base url] <path>")
+		os.Exit(2)
+	}
+
+	configuration, err := app.LoadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	links, err := ext.NewShareLinks(configuration.Ext.Share)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println(*base + links.Sign(flags.Arg(0), time.Now().Add(*expires), *prefix))
+}
@@ -34,11 +34,27 @@ type ExtensionsConfig struct {
 	Prefix            string `json:"prefix"`
 	DefaultHTML       string `json:"defaulthtml"`
 	DefaultHTMLs      []string
-	FavIcon           string `json:"favicon"`
-	CacheSize         int    `json:"cachesize"`
-	MarkdownTemplate  string `json:"markdowntemplate"`
-	ListFolder        bool   `json:"listfolder"`
-	ListFolderObjects string `json:"listfolderobjects"`
+	FavIcon           string           `json:"favicon"`
+	CacheSize         int              `json:"cachesize"`
+	MarkdownTemplate  string           `json:"markdowntemplate"`
+	Markdown          MarkdownConfig   `json:"markdown"`
+	Source            SourceConfig     `json:"source"`
+	DataViewer        DataViewerConfig `json:"dataviewer"`
+	ListFolder        bool             `json:"listfolder"`
+	ListFolderObjects string           `json:"listfolderobjects"`
+	Listing           ListingConfig    `json:"listing"`
+	Cors              CorsConfig       `json:"cors"`
+	BasicAuth         BasicAuthConfig  `json:"basicauth"`
+	OIDC              OIDCConfig       `json:"oidc"`
+	AuthPolicies      []AuthPolicy     `json:"authpolicies"`
+	Share             ShareConfig      `json:"share"`
+	Presign           PresignConfig    `json:"presign"`
+	TrustedProxies    string           `json:"trustedproxies"`
+	BasePath          string           `json:"basepath"`
+	TrailingSlash     bool             `json:"trailingslash"`
+	Access            AccessConfig     `json:"access"`
+	RateLimit         RateLimitConfig  `json:"ratelimit"`
+	Throttle          ThrottleConfig   `json:"throttle"`
 }
 
 // configFilePath returns the location of the config file.
@@ -6,6 +6,7 @@
 	"go.uber.org/zap"
 
 	core "github.com/e2fyi/minio-web/pkg/core"
+	ext "github.com/e2fyi/minio-web/pkg/ext"
 	minio "github.com/e2fyi/minio-web/pkg/minio"
 )
 
@@ -35,3 +36,42 @@
 
 // MinioConfig is an alias for core.Config
 type MinioConfig = minio.Config
+
+// CorsConfig is an alias for ext.CorsConfig
+type CorsConfig = ext.CorsConfig
+
+// BasicAuthConfig is an alias for ext.BasicAuthConfig
+type BasicAuthConfig = ext.BasicAuthConfig
+
+// OIDCConfig is an alias for ext.OIDCConfig
+type OIDCConfig = ext.OIDCConfig
+
+// AuthPolicy is an alias for ext.AuthPolicy
+type AuthPolicy = ext.AuthPolicy
+
+// ShareConfig is an alias for ext.ShareConfig
+type ShareConfig = ext.ShareConfig
+
+// PresignConfig is an alias for ext.PresignConfig
+type PresignConfig = ext.PresignConfig
+
+// AccessConfig is an alias for ext.AccessConfig
+type AccessConfig = ext.AccessConfig
+
+// RateLimitConfig is an alias for ext.RateLimitConfig
+type RateLimitConfig = ext.RateLimitConfig
+
+// ThrottleConfig is an alias for ext.ThrottleConfig
+type ThrottleConfig = ext.ThrottleConfig
+
+// ListingConfig is an alias for ext.ListingConfig
+type ListingConfig = ext.ListingConfig
+
+// MarkdownConfig is an alias for ext.MarkdownConfig
+type MarkdownConfig = ext.MarkdownConfig
+
+// SourceConfig is an alias for ext.SourceConfig
+type SourceConfig = ext.SourceConfig
+
+// DataViewerConfig is an alias for ext.DataViewerConfig
+type DataViewerConfig = ext.DataViewerConfig
@@ -81,6 +81,20 @@ func (c *Core) ApplyHeader(decorator HeaderHandlerDecorator) *Core {
 	return c
 }
 
+// ApplyRequest decorate the http request handler.
+func (c *Core) ApplyRequest(decorator RequestHandlerDecorator) *Core {
+	c.RequestDecorators = append(c.RequestDecorators, decorator)
+	return c
+}
+
+// ApplyAccess decorate the http request handler with a decorator which allows
+// or denies the requests (e.g. authentication), which is also used to
+// authorize the access to other urls.
+func (c *Core) ApplyAccess(decorator RequestHandlerDecorator) *Core {
+	c.AccessDecorators = append(c.AccessDecorators, decorator)
+	return c.ApplyRequest(decorator)
+}
+
 // ApplyExtension applies an extension on core state.
 func (c *Core) ApplyExtension(ext Extension) *Core {
 	msg, err := ext(c)
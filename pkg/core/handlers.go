@@ -9,6 +9,7 @@
 
 // ResourceInfo describes the metadata of the resource.
 type ResourceInfo struct {
+	Bucket       string
 	Key          string
 	Size         int64
 	ETag         string
@@ -33,6 +34,13 @@ type Handlers struct {
 	ListFolder func(url string) (Resource, error)
 	SetHeaders func(w http.ResponseWriter, info ResourceInfo)
 	Serve      func(w http.ResponseWriter, r Resource) error
+	// RequestDecorators decorate the http handler returned by Handler (e.g.
+	// to handle CORS or authentication before any resource is retrieved).
+	RequestDecorators []RequestHandlerDecorator
+	// AccessDecorators are the request decorators which allow or deny the
+	// requests (e.g. authentication), also used to authorize the access to
+	// other urls (e.g. the objects found by a search).
+	AccessDecorators []RequestHandlerDecorator
 	Sugared
 }
 
@@ -54,6 +62,12 @@ type Handlers struct {
 // HeaderHandlerDecorator decorates a HeaderHandler.
 type HeaderHandlerDecorator = func(HeaderHandler) HeaderHandler
 
+// RequestHandler handles a http request.
+type RequestHandler = func(w http.ResponseWriter, r *http.Request)
+
+// RequestHandlerDecorator decorates a RequestHandler.
+type RequestHandlerDecorator = func(RequestHandler) RequestHandler
+
 // SetDefaultHeaders set headers for the http response.
 func SetDefaultHeaders(w http.ResponseWriter, info ResourceInfo) {
 	w.Header().Set("Content-Type", info.ContentType)
@@ -81,7 +95,7 @@ func (h *Handlers) Handler() func(w http.ResponseWriter, r *http.Request) {
 		h.SetHeaders = SetDefaultHeaders
 	}
 
-	return func(w http.ResponseWriter, r *http.Request) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case "HEAD":
 			h.HeadHandler(w, r)
@@ -91,6 +105,53 @@ func (h *Handlers) Handler() func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(405)
 		}
 	}
+	// decorators applied later are executed first
+	for _, decorator := range h.RequestDecorators {
+		handler = decorator(handler)
+	}
+	return handler
+}
+
+// discardResponseWriter discards the response of the access decorators when
+// checking the access to other urls.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = http.Header{}
+	}
+	return w.header
+}
+
+func (w *discardResponseWriter) Write(data []byte) (int, error) { return len(data), nil }
+
+func (w *discardResponseWriter) WriteHeader(statusCode int) {}
+
+// AuthorizeURL runs the access decorators for the request as if it was
+// requesting another url (e.g. the folder of a search), and returns whether
+// it is allowed. The response of a denied request (e.g. 401) is written.
+func (h *Handlers) AuthorizeURL(w http.ResponseWriter, r *http.Request, url string) bool {
+	allowed := false
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		allowed = true
+	}
+	for _, decorator := range h.AccessDecorators {
+		handler = decorator(handler)
+	}
+	request := r.Clone(r.Context())
+	request.Method = http.MethodGet
+	request.URL.Path = url
+	request.URL.RawPath = ""
+	handler(w, request)
+	return allowed
+}
+
+// AllowedURL checks whether the request is allowed to access another url,
+// without writing any response.
+func (h *Handlers) AllowedURL(r *http.Request, url string) bool {
+	return h.AuthorizeURL(&discardResponseWriter{}, r, url)
 }
 
 // HeadHandler handles the request when method is HEAD.
@@ -121,9 +182,15 @@ func (h *Handlers) GetHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(404)
 		return
 	}
+	h.ServeResource(w, r, res)
+}
+
+// ServeResource sets the headers and serves a retrieved Resource.
+func (h *Handlers) ServeResource(w http.ResponseWriter, r *http.Request, res Resource) {
+	url := r.URL.Path
 
 	h.SetHeaders(w, res.Info)
-	err = h.Serve(w, res)
+	err := h.Serve(w, res)
 	if res.Msg != "" {
 		h.Sugar.Info(res.Msg)
 	}
@@ -0,0 +1,113 @@
+package ext
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+)
+
+// AccessConfig is used to config the ip access extension.
+type AccessConfig struct {
+	// Comma separated list of ips or CIDRs allowed to access all urls.
+	Allow string `json:"allow"`
+	// Comma separated list of ips or CIDRs denied from accessing all urls.
+	Deny string `json:"deny"`
+	// Rules for specific url prefixes (the longest matching prefix applies).
+	Rules []AccessRule `json:"rules"`
+}
+
+// AccessRule describes the ips allowed or denied for a url prefix. An empty
+// allow list allows any ip which is not denied.
+type AccessRule struct {
+	Prefix string `json:"prefix"`
+	Allow  string `json:"allow"`
+	Deny   string `json:"deny"`
+}
+
+// Access provides the decorator to allow or deny requests based on the ip of
+// the client.
+type Access struct {
+	rules    []accessRule
+	resolver *ClientIPResolver
+}
+
+// accessRule is a compiled AccessRule.
+type accessRule struct {
+	prefix string
+	allow  []*net.IPNet
+	deny   []*net.IPNet
+}
+
+// AccessExtension installs the extension to allow or deny requests based on
+// the ip of the client before any resource is retrieved.
+func AccessExtension(config AccessConfig, trustedProxies string) Extension {
+	return func(c *Core) (string, error) {
+		if config.Allow == "" && config.Deny == "" && len(config.Rules) == 0 {
+			return "ip access: disabled", nil
+		}
+		access, err := NewAccess(config, trustedProxies)
+		if err != nil {
+			return "ip access: errored", err
+		}
+		c.ApplyAccess(access.CheckAccess)
+		return fmt.Sprintf("ip access: %d rule(s)", len(access.rules)), nil
+	}
+}
+
+// NewAccess creates a new Access object.
+func NewAccess(config AccessConfig, trustedProxies string) (*Access, error) {
+	resolver, err := NewClientIPResolver(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	rules := config.Rules
+	if config.Allow != "" || config.Deny != "" {
+		rules = append(rules, AccessRule{Prefix: "/", Allow: config.Allow, Deny: config.Deny})
+	}
+
+	access := &Access{resolver: resolver}
+	for _, rule := range rules {
+		allow, err := parseCIDRs(rule.Allow)
+		if err != nil {
+			return nil, err
+		}
+		deny, err := parseCIDRs(rule.Deny)
+		if err != nil {
+			return nil, err
+		}
+		access.rules = append(access.rules, accessRule{prefix: rule.Prefix, allow: allow, deny: deny})
+	}
+	// longest prefix is matched first
+	sort.SliceStable(access.rules, func(i, j int) bool {
+		return len(access.rules[i].prefix) > len(access.rules[j].prefix)
+	})
+	return access, nil
+}
+
+// isAllowed checks whether the ip can access the url.
+func (a *Access) isAllowed(url string, ip net.IP) bool {
+	for _, rule := range a.rules {
+		if !hasPathPrefix(url, rule.prefix) {
+			continue
+		}
+		if ip == nil || containsIP(rule.deny, ip) {
+			return false
+		}
+		return len(rule.allow) == 0 || containsIP(rule.allow, ip)
+	}
+	return true
+}
+
+// CheckAccess decorates a RequestHandler to deny requests from ips which are
+// not allowed.
+func (a *Access) CheckAccess(handler RequestHandler) RequestHandler {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.isAllowed(r.URL.Path, a.resolver.ClientIP(r)) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		handler(w, r)
+	}
+}
@@ -0,0 +1,328 @@
+package ext
+
+import (
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// asciidocHeading matches the titles (e.g. == Section).
+	asciidocHeading = regexp.MustCompile(`^(={1,6})\s+(.+?)(?:\s+=+)?\s*$`)
+	// asciidocAttribute matches the attribute entries (e.g. :version: 1.0).
+	asciidocAttribute = regexp.MustCompile(`^:(!?[\w-]+!?):\s*(.*)$`)
+	// asciidocDelimiter matches the delimiters of the blocks (e.g. ----).
+	asciidocDelimiter = regexp.MustCompile(`^(-{4,}|\.{4,}|\+{4,}|/{4,}|={4,}|\*{4,}|_{4,}|\|={3,})\s*$`)
+	// asciidocAdmonition matches the admonition paragraphs (e.g. NOTE: text).
+	asciidocAdmonition = regexp.MustCompile(`^(NOTE|TIP|IMPORTANT|WARNING|CAUTION):\s+(.*)$`)
+	// asciidocImage matches the block images (e.g. image::a.png[alt]).
+	asciidocImage = regexp.MustCompile(`^image::([^\[]+)\[([^\],]*)[^\]]*\]\s*$`)
+	// asciidocList matches the items of the unordered and ordered lists.
+	asciidocList = regexp.MustCompile(`^(\*+|-|\.+)\s+(.*)$`)
+	// asciidocColumns matches the column specifiers with a multiplier (e.g.
+	// 3* or 2*a).
+	asciidocColumns = regexp.MustCompile(`^(\d+)\*`)
+	// asciidocDescription matches the items of the description lists (e.g.
+	// term:: definition).
+	asciidocDescription = regexp.MustCompile(`^(\S.*?)::(?:\s+(.*))?$`)
+	// asciidocInline matches the inline markup, i.e. code, attribute
+	// references, cross references, images and links.
+	asciidocInline = regexp.MustCompile("`[^`]+`" +
+		`|\{([\w-]+)\}` +
+		`|<<([^,>]+)(?:,\s*([^>]+))?>>` +
+		`|image:([^:\s\[][^\s\[]*)\[([^\],]*)[^\]]*\]` +
+		`|(?:xref|link):([^\s\[]+)\[([^\]]*)\]` +
+		`|((?:https?|ftp)://[^\s\[\]<>]+|mailto:[^\s\[\]<>]+)\[([^\]]*)\]`)
+	// asciidocBold matches the constrained bold text (e.g. *bold*).
+	asciidocBold = regexp.MustCompile(`(^|[^\w*])\*([^*\s](?:[^*]*[^*\s])?)\*($|[^\w*])`)
+)
+
+// asciidocAdmonitions are the labels of the admonitions.
+var asciidocAdmonitions = map[string]string{
+	"NOTE":      "Note",
+	"TIP":       "Tip",
+	"IMPORTANT": "Important",
+	"WARNING":   "Warning",
+	"CAUTION":   "Caution",
+}
+
+// asciidocConverter converts the common subset of AsciiDoc to markdown.
+type asciidocConverter struct {
+	attributes map[string]string
+}
+
+// asciidocToMarkdown converts an AsciiDoc document to markdown.
+func asciidocToMarkdown(content []byte) ([]byte, error) {
+	c := asciidocConverter{attributes: map[string]string{}}
+	return []byte(strings.Join(c.convert(splitLines(content)), "\n")), nil
+}
+
+// blockAttributes parses the positional and named attributes of a block (e.g.
+// [source,go] or [cols="1,2"]). The style is the first positional attribute
+// without its shorthands (e.g. source of source%linenums).
+func blockAttributes(line string) (style string, positional []string, named map[string]string) {
+	named = map[string]string{}
+	var attributes []string
+	var current strings.Builder
+	quoted := false
+	for _, r := range strings.TrimSuffix(strings.TrimPrefix(line, "["), "]") {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			attributes = append(attributes, strings.TrimSpace(current.String()))
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	attributes = append(attributes, strings.TrimSpace(current.String()))
+	for _, attribute := range attributes {
+		if i := strings.Index(attribute, "="); i > 0 {
+			named[strings.TrimSpace(attribute[:i])] = strings.TrimSpace(attribute[i+1:])
+			continue
+		}
+		positional = append(positional, attribute)
+	}
+	if len(positional) > 0 {
+		style = positional[0]
+		if i := strings.IndexAny(style, "#.%"); i >= 0 {
+			style = style[:i]
+		}
+	}
+	return style, positional, named
+}
+
+// inline converts the inline markup of a line to markdown.
+func (c asciidocConverter) inline(line string) string {
+	return replaceInline(line, asciidocInline, func(groups []string) string {
+		switch {
+		case groups[1] != "":
+			if value, ok := c.attributes[groups[1]]; ok {
+				return escapeHTML(value)
+			}
+			return groups[0]
+		case groups[2] != "":
+			return link(groups[3], xref(strings.TrimSpace(groups[2])))
+		case groups[4] != "":
+			return "![" + groups[5] + "](" + groups[4] + ")"
+		case groups[6] != "" && strings.HasPrefix(groups[0], "xref:"):
+			return link(groups[7], xref(groups[6]))
+		case groups[6] != "":
+			return link(groups[7], groups[6])
+		case groups[8] != "":
+			return link(groups[9], groups[8])
+		}
+		// code
+		return groups[0]
+	}, func(text string) string {
+		text = escapeHTML(text)
+		// the adjacent matches share their boundaries
+		for bold := ""; bold != text; {
+			bold, text = text, asciidocBold.ReplaceAllString(text, "$1**$2**$3")
+		}
+		return text
+	})
+}
+
+// xref returns the url of a cross reference to an id or a document (e.g.
+// other.adoc#id). The generated ids (e.g. _section_title) are converted to the
+// anchors of the titles (e.g. section-title).
+func xref(target string) string {
+	if i := strings.Index(target, "#"); i > 0 {
+		return strings.TrimSuffix(target, "#")
+	}
+	if strings.HasSuffix(target, ".adoc") {
+		return target
+	}
+	if strings.HasPrefix(target, "_") {
+		return "#" + strings.ReplaceAll(strings.TrimPrefix(target, "_"), "_", "-")
+	}
+	return "#" + target
+}
+
+// link returns a markdown link, whose text defaults to its url.
+func link(text string, url string) string {
+	if text == "" {
+		text = url
+	}
+	return "[" + text + "](" + url + ")"
+}
+
+// tableColumns returns the number of columns of the cols attribute of a table
+// (e.g. "1,2" or "3*").
+func tableColumns(cols string) int {
+	n := 0
+	for _, spec := range strings.Split(cols, ",") {
+		if match := asciidocColumns.FindStringSubmatch(strings.TrimSpace(spec)); match != nil {
+			multiplier, _ := strconv.Atoi(match[1])
+			n += multiplier
+			continue
+		}
+		n++
+	}
+	return n
+}
+
+// table converts the cells of a table to markdown. The number of columns is
+// given by the cols attribute or the cells of the first row, which is the
+// header.
+func (c asciidocConverter) table(lines []string, named map[string]string) []string {
+	var cells []string
+	columns := 0
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "|") {
+			// continuation of the last cell
+			if len(cells) > 0 {
+				cells[len(cells)-1] += " " + c.inline(line)
+			}
+			continue
+		}
+		row := strings.Split(line, "|")[1:]
+		if columns == 0 {
+			columns = len(row)
+		}
+		for _, cell := range row {
+			cells = append(cells, c.inline(strings.TrimSpace(cell)))
+		}
+	}
+	if cols := strings.Trim(named["cols"], `"'`); cols != "" {
+		columns = tableColumns(cols)
+	}
+	if columns == 0 {
+		return nil
+	}
+	var rows [][]string
+	for i := 0; i < len(cells); i += columns {
+		end := i + columns
+		if end > len(cells) {
+			end = len(cells)
+		}
+		rows = append(rows, cells[i:end])
+	}
+	return markdownTable(rows)
+}
+
+// block converts a delimited block, with the style and attributes of its
+// attribute line.
+func (c asciidocConverter) block(delimiter string, lines []string, style string, positional []string, named map[string]string) []string {
+	label := asciidocAdmonitions[strings.ToUpper(style)]
+	switch delimiter[0] {
+	case '-', '.':
+		language := ""
+		switch {
+		case style == "source" && len(positional) > 1:
+			language = positional[1]
+		case style != "source" && style != "listing" && style != "literal":
+			language = style
+		}
+		return codeFence(language, lines)
+	case '+':
+		if style == "stem" || style == "latexmath" {
+			return codeFence("math", lines)
+		}
+		return append(append([]string{""}, lines...), "")
+	case '/':
+		return nil
+	case '|':
+		return c.table(lines, named)
+	case '_':
+		return quote("", c.convert(lines))
+	}
+	// example, sidebar and admonition blocks
+	if label != "" || delimiter[0] == '*' {
+		return quote(label, c.convert(lines))
+	}
+	return c.convert(lines)
+}
+
+// convert converts the lines of an AsciiDoc document (or block) to markdown.
+func (c asciidocConverter) convert(lines []string) []string {
+	var converted []string
+	var style string
+	var positional []string
+	var named map[string]string
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " ")
+
+		if match := asciidocDelimiter.FindStringSubmatch(line); match != nil {
+			end := i + 1
+			for end < len(lines) && strings.TrimRight(lines[end], " ") != match[1] {
+				end++
+			}
+			converted = append(converted, c.block(match[1], lines[i+1:end], style, positional, named)...)
+			style, positional, named = "", nil, nil
+			i = end
+			continue
+		}
+
+		switch {
+		case line == "":
+			style, positional, named = "", nil, nil
+			converted = append(converted, "")
+		case (line[0] == ' ' || line[0] == '\t') && (i == 0 || strings.TrimSpace(lines[i-1]) == "") && !asciidocList.MatchString(strings.TrimSpace(line)):
+			// literal paragraphs, i.e. the indented lines up to a blank line
+			end := i + 1
+			for end < len(lines) && strings.TrimSpace(lines[end]) != "" {
+				end++
+			}
+			converted = append(converted, codeFence("", dedent(lines[i:end]))...)
+			i = end - 1
+		case strings.HasPrefix(line, "//"), line == "--", line == "<<<":
+			// comments, open blocks and page breaks
+		case asciidocAttribute.MatchString(line):
+			match := asciidocAttribute.FindStringSubmatch(line)
+			if name := strings.Trim(match[1], "!"); name != match[1] {
+				delete(c.attributes, name)
+			} else {
+				c.attributes[name] = match[2]
+			}
+		case strings.HasPrefix(line, "[[") && strings.HasSuffix(line, "]]"):
+			id := strings.Split(strings.Trim(line, "[]"), ",")[0]
+			converted = append(converted, `<a id="`+template.HTMLEscapeString(id)+`"></a>`, "")
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			style, positional, named = blockAttributes(line)
+		case asciidocHeading.MatchString(line):
+			match := asciidocHeading.FindStringSubmatch(line)
+			converted = append(converted, "", strings.Repeat("#", len(match[1]))+" "+c.inline(match[2]), "")
+		case line == "'''":
+			converted = append(converted, "", "---", "")
+		case asciidocImage.MatchString(line):
+			match := asciidocImage.FindStringSubmatch(line)
+			converted = append(converted, "!["+match[2]+"]("+match[1]+")")
+		case asciidocAdmonition.MatchString(line):
+			// the lazy continuation lines are in the quote too
+			match := asciidocAdmonition.FindStringSubmatch(line)
+			converted = append(converted, "> **"+asciidocAdmonitions[match[1]]+":** "+c.inline(match[2]))
+		case len(line) > 1 && line[0] == '.' && line[1] != '.' && line[1] != ' ':
+			// titles of the blocks
+			converted = append(converted, "**"+c.inline(line[1:])+"**", "")
+		case asciidocList.MatchString(strings.TrimLeft(line, " \t")):
+			match := asciidocList.FindStringSubmatch(strings.TrimLeft(line, " \t"))
+			marker, depth := "- ", len(match[1])
+			if match[1] == "-" {
+				depth = 1
+			}
+			if match[1][0] == '.' {
+				marker = "1. "
+			}
+			converted = append(converted, strings.Repeat("  ", depth-1)+marker+c.inline(match[2]))
+		case line == "+":
+			// list continuations
+			converted = append(converted, "")
+		case asciidocDescription.MatchString(line):
+			match := asciidocDescription.FindStringSubmatch(line)
+			converted = append(converted, "**"+c.inline(match[1])+"**", "", c.inline(match[2]), "")
+		case strings.HasSuffix(line, " +"):
+			converted = append(converted, c.inline(strings.TrimSuffix(line, " +"))+`\`)
+		default:
+			converted = append(converted, c.inline(line))
+		}
+	}
+	return converted
+}
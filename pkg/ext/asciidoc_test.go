@@ -0,0 +1,89 @@
+package ext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAsciidocToMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		asciidoc string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "sections",
+			asciidoc: "= Title\n\n== Section *One*\n\ntext\n\n=== Sub Section ===\n",
+			contains: []string{"\n# Title\n", "\n## Section **One**\n", "\ntext\n", "\n### Sub Section\n"},
+		},
+		{
+			name:     "table with a header row",
+			asciidoc: "|===\n|Name |Size\n|a.md |1\n|===\n",
+			contains: []string{"| Name | Size |\n| --- | --- |\n| a.md | 1 |"},
+		},
+		{
+			name:     "table with a cell per line",
+			asciidoc: "[cols=\"1,2\"]\n|===\n|Name\n|Size\n|a.md\n|1\n|===\n",
+			contains: []string{"| Name | Size |\n| --- | --- |\n| a.md | 1 |"},
+		},
+		{
+			name:     "table with a cols multiplier",
+			asciidoc: "[cols=\"3*\"]\n|===\n|a\n|b\n|c\n|1\n|2\n|3\n|===\n",
+			contains: []string{"| a | b | c |\n| --- | --- | --- |\n| 1 | 2 | 3 |"},
+		},
+		{
+			name:     "source block",
+			asciidoc: "[source,go]\n----\nfunc main() {}\n----\n",
+			contains: []string{"```go\nfunc main() {}\n```"},
+		},
+		{
+			name:     "literal block",
+			asciidoc: "....\nliteral *text*\n....\n",
+			contains: []string{"```\nliteral *text*\n```"},
+		},
+		{
+			name:     "indented literal paragraph",
+			asciidoc: "text\n\n  indented *text*\n    line\n\nafter\n",
+			contains: []string{"text\n", "```\nindented *text*\n  line\n```", "\nafter"},
+		},
+		{
+			name:     "indented list items",
+			asciidoc: "  * item\n",
+			contains: []string{"- item"},
+			excludes: []string{"```"},
+		},
+		{
+			name:     "cross references",
+			asciidoc: "See <<_section_one,Section One>>, xref:install[Install] and link:install.html[Install].",
+			contains: []string{"[Section One](#section-one)", "[Install](#install)", "[Install](install.html)"},
+		},
+		{
+			name:     "cross references to other documents",
+			asciidoc: "See <<other.adoc#intro,Intro>>, xref:other.adoc#intro[Intro] and <<other.adoc#>>, xref:other.adoc[].",
+			contains: []string{"[Intro](other.adoc#intro),", ", [Intro](other.adoc#intro) and", "[other.adoc](other.adoc),", "[other.adoc](other.adoc)."},
+		},
+		{
+			name:     "attributes",
+			asciidoc: ":version: 1.0\n\nVersion {version} of {unknown}.",
+			contains: []string{"Version 1.0 of {unknown}."},
+		},
+	}
+	for _, test := range tests {
+		converted, err := asciidocToMarkdown([]byte(test.asciidoc))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		for _, s := range test.contains {
+			if !strings.Contains(string(converted), s) {
+				t.Errorf("%s: expected %q in %q", test.name, s, converted)
+			}
+		}
+		for _, s := range test.excludes {
+			if strings.Contains(string(converted), s) {
+				t.Errorf("%s: unexpected %q in %q", test.name, s, converted)
+			}
+		}
+	}
+}
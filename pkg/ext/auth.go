@@ -0,0 +1,94 @@
+package ext
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// identityKey is the context key for the authenticated identity.
+type identityKey struct{}
+
+// authCacheKey is the context key for the authentications of a request.
+type authCacheKey struct{}
+
+// authCache holds the authentications of a request whose access to many urls
+// is checked (e.g. the objects of a listing), so that its credentials are
+// only verified once for each realm or token.
+type authCache struct {
+	results map[string]authResult
+	mutex   sync.Mutex
+}
+
+// authResult is the identity authenticated by some credentials, if valid.
+type authResult struct {
+	identity Identity
+	ok       bool
+}
+
+// Identity describes an authenticated user.
+type Identity struct {
+	// Name of the user (e.g. username or email).
+	Name string
+	// Provider which authenticated the user (e.g. basic, oidc).
+	Provider string
+	// Email of the user if known.
+	Email string
+	// Groups the user belongs to if known.
+	Groups []string
+}
+
+// WithIdentity returns a shallow copy of the request with the authenticated
+// identity attached to its context.
+func WithIdentity(r *http.Request, identity Identity) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), identityKey{}, identity))
+}
+
+// GetIdentity returns the authenticated identity of the request if any.
+func GetIdentity(r *http.Request) (Identity, bool) {
+	identity, ok := r.Context().Value(identityKey{}).(Identity)
+	return identity, ok
+}
+
+// withAuthCache returns a shallow copy of the request whose authentications
+// are remembered, e.g. to check its access to many urls.
+func withAuthCache(r *http.Request) *http.Request {
+	cache := &authCache{results: map[string]authResult{}}
+	return r.WithContext(context.WithValue(r.Context(), authCacheKey{}, cache))
+}
+
+// authenticate returns the identity authenticated by the credentials of the
+// request, which are only verified once for each key (e.g. realm) if the
+// request remembers its authentications.
+func authenticate(r *http.Request, key string, verify func() (Identity, bool)) (Identity, bool) {
+	cache, ok := r.Context().Value(authCacheKey{}).(*authCache)
+	if !ok {
+		return verify()
+	}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	result, ok := cache.results[key]
+	if !ok {
+		result.identity, result.ok = verify()
+		cache.results[key] = result
+	}
+	return result.identity, result.ok
+}
+
+// hasPathPrefix checks whether the url is inside the prefix on a path
+// segment boundary, i.e. /docs covers /docs and /docs/a but not /docs-a.
+func hasPathPrefix(url string, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return url == prefix || strings.HasPrefix(url, prefix+"/")
+}
+
+// hasAnyPrefix checks whether the url is inside any of the prefixes.
+func hasAnyPrefix(url string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if hasPathPrefix(url, prefix) {
+			return true
+		}
+	}
+	return false
+}
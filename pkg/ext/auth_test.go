@@ -0,0 +1,61 @@
+package ext
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHasPathPrefix(t *testing.T) {
+	tests := []struct {
+		url    string
+		prefix string
+		inside bool
+	}{
+		{"/docs", "/docs", true},
+		{"/docs/", "/docs", true},
+		{"/docs/a.md", "/docs", true},
+		{"/docs/a.md", "/docs/", true},
+		{"/docs", "/docs/", true},
+		{"/docs-public/a.md", "/docs", false},
+		{"/docs-public/a.md", "/docs/", false},
+		{"/healthz-secret", "/health", false},
+		{"/health", "/health", true},
+		{"/a.md", "/", true},
+		{"/", "/", true},
+	}
+	for _, test := range tests {
+		if hasPathPrefix(test.url, test.prefix) != test.inside {
+			t.Errorf("%s inside %s: expected %t", test.url, test.prefix, test.inside)
+		}
+	}
+}
+
+func TestAuthenticateOnce(t *testing.T) {
+	calls := 0
+	verify := func() (Identity, bool) {
+		calls++
+		return Identity{Name: "a"}, calls == 1
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	authenticate(r, "realm", verify)
+	authenticate(r, "realm", verify)
+	if calls != 2 {
+		t.Errorf("expected the credentials to be verified for each url, got %d verification(s)", calls)
+	}
+
+	calls = 0
+	r = withAuthCache(r)
+	for i := 0; i < 3; i++ {
+		if identity, ok := authenticate(r, "realm", verify); !ok || identity.Name != "a" {
+			t.Errorf("expected the remembered identity, got %v %t", identity, ok)
+		}
+	}
+	if _, ok := authenticate(r, "other realm", verify); ok {
+		t.Errorf("expected the credentials to be verified again for another realm")
+	}
+	if calls != 2 {
+		t.Errorf("expected the credentials to be verified once per realm, got %d verification(s)", calls)
+	}
+}
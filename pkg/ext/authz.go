@@ -0,0 +1,153 @@
+package ext
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	glob "github.com/gobwas/glob"
+)
+
+// AuthPolicy grants the users matching any of the criteria (groups, emails
+// or email domains) access to the urls matching the globs.
+type AuthPolicy struct {
+	// Comma separated list of url globs (e.g. /docs/internal/**). A folder
+	// glob (/**) also covers the url of the folder (e.g. /docs/internal).
+	Paths string `json:"paths"`
+	// Comma separated list of groups.
+	Groups string `json:"groups"`
+	// Comma separated list of emails.
+	Emails string `json:"emails"`
+	// Comma separated list of email domains (e.g. abc.com).
+	Domains string `json:"domains"`
+}
+
+// Authorization provides the decorator to authorize authenticated users
+// according to a list of policies. Urls which do not match any policy are
+// not restricted.
+type Authorization struct {
+	policies []policy
+}
+
+// policy is a compiled AuthPolicy.
+type policy struct {
+	paths   []glob.Glob
+	groups  []string
+	emails  []string
+	domains []string
+}
+
+// AuthorizationExtension installs the extension to authorize authenticated
+// users to access urls. Must be applied before the authentication extensions
+// so that the identity is known when authorizing.
+func AuthorizationExtension(policies []AuthPolicy) Extension {
+	return func(c *Core) (string, error) {
+		if len(policies) == 0 {
+			return "authorization: disabled", nil
+		}
+		authz, err := NewAuthorization(policies)
+		if err != nil {
+			return "authorization: errored", err
+		}
+		c.ApplyAccess(authz.Authorize)
+		return fmt.Sprintf("authorization: %d policies", len(policies)), nil
+	}
+}
+
+// NewAuthorization creates a new Authorization object.
+func NewAuthorization(policies []AuthPolicy) (*Authorization, error) {
+	authz := &Authorization{}
+	for _, p := range policies {
+		compiled := policy{
+			groups:  splitList(p.Groups),
+			emails:  splitList(p.Emails),
+			domains: splitList(p.Domains)}
+		for _, path := range splitList(p.Paths) {
+			paths := []string{path}
+			// the folder url without the trailing slash (e.g. /docs/internal)
+			// can also serve the content of the folder (e.g. index files)
+			if folder := strings.TrimSuffix(path, "/**"); folder != path && folder != "" {
+				paths = append(paths, folder)
+			}
+			for _, path := range paths {
+				g, err := glob.Compile(path, '/')
+				if err != nil {
+					return nil, err
+				}
+				compiled.paths = append(compiled.paths, g)
+			}
+		}
+		authz.policies = append(authz.policies, compiled)
+	}
+	return authz, nil
+}
+
+// matchesPath checks whether the url is covered by the policy.
+func (p policy) matchesPath(url string) bool {
+	for _, g := range p.paths {
+		if g.Match(url) {
+			return true
+		}
+	}
+	return false
+}
+
+// allows checks whether the identity fulfils any criteria of the policy.
+func (p policy) allows(identity Identity) bool {
+	email := strings.ToLower(identity.Email)
+	for _, e := range p.emails {
+		if strings.ToLower(e) == email {
+			return true
+		}
+	}
+	for _, domain := range p.domains {
+		if email != "" && strings.HasSuffix(email, "@"+strings.ToLower(domain)) {
+			return true
+		}
+	}
+	for _, group := range p.groups {
+		for _, g := range identity.Groups {
+			if g == group {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isAllowed returns whether the url is restricted by any policy, and if so
+// whether the identity is allowed by any of the policies.
+func (a *Authorization) isAllowed(url string, identity Identity, authenticated bool) (restricted bool, allowed bool) {
+	for _, p := range a.policies {
+		if !p.matchesPath(url) {
+			continue
+		}
+		restricted = true
+		if authenticated && p.allows(identity) {
+			return true, true
+		}
+	}
+	return restricted, false
+}
+
+// Authorize decorates a RequestHandler to only allow authorized users.
+func (a *Authorization) Authorize(handler RequestHandler) RequestHandler {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity, authenticated := GetIdentity(r)
+		// signed urls are authorized by their signature
+		if authenticated && identity.Provider == shareProvider {
+			handler(w, r)
+			return
+		}
+		restricted, allowed := a.isAllowed(r.URL.Path, identity, authenticated)
+		switch {
+		case !restricted || allowed:
+			handler(w, r)
+		case !authenticated:
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}
+}
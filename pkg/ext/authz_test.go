@@ -0,0 +1,47 @@
+package ext
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthorizeFolder(t *testing.T) {
+	authz, err := NewAuthorization([]AuthPolicy{
+		{Paths: "/internal/**", Groups: "admin"},
+		{Paths: "/teams/*/private/**", Groups: "admin"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := authz.Authorize(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		url      string
+		identity *Identity
+		status   int
+	}{
+		{"/internal", nil, http.StatusUnauthorized},
+		{"/internal/", nil, http.StatusUnauthorized},
+		{"/internal/index.html", nil, http.StatusUnauthorized},
+		{"/internal", &Identity{Name: "a", Groups: []string{"admin"}}, http.StatusOK},
+		{"/internal/", &Identity{Name: "a", Groups: []string{"admin"}}, http.StatusOK},
+		{"/internal", &Identity{Name: "b", Groups: []string{"users"}}, http.StatusForbidden},
+		{"/internal-public", nil, http.StatusOK},
+		{"/internal-public/", nil, http.StatusOK},
+		{"/teams/a/private", nil, http.StatusUnauthorized},
+		{"/teams/a/private/", nil, http.StatusUnauthorized},
+		{"/teams/a/public/", nil, http.StatusOK},
+		{"/", nil, http.StatusOK},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, test.url, nil)
+		if test.identity != nil {
+			r = WithIdentity(r, *test.identity)
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		if w.Code != test.status {
+			t.Errorf("%s (%v): expected %d, got %d", test.url, test.identity, test.status, w.Code)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package ext
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BasicAuthConfig is used to config the basic authentication extension.
+type BasicAuthConfig struct {
+	// Path to the htpasswd file protecting all urls.
+	HtpasswdFile string `json:"htpasswdfile"`
+	// Name of the realm protecting all urls.
+	Realm string `json:"realm"`
+	// Comma separated list of url prefixes which do not require
+	// authentication (e.g. health endpoints).
+	Exempt string `json:"exempt"`
+	// Realms protecting specific url prefixes.
+	Realms []BasicAuthRealm `json:"realms"`
+}
+
+// BasicAuthRealm describes a realm protecting a url prefix.
+type BasicAuthRealm struct {
+	Prefix       string `json:"prefix"`
+	Realm        string `json:"realm"`
+	HtpasswdFile string `json:"htpasswdfile"`
+}
+
+// BasicAuth provides the decorator to authenticate requests with http basic
+// authentication.
+type BasicAuth struct {
+	realms []*realm
+	exempt []string
+}
+
+// realm is a url prefix protected by a htpasswd file.
+type realm struct {
+	prefix   string
+	name     string
+	htpasswd *htpasswd
+}
+
+// htpasswd is a htpasswd file which is reloaded when modified.
+type htpasswd struct {
+	path      string
+	modTime   time.Time
+	checkedAt time.Time
+	users     map[string]string
+	mutex     sync.RWMutex
+}
+
+// dummyHash is compared with the password of unknown users, so that the
+// response time does not reveal which users exist.
+var dummyHash = []byte("$2a$10$nEA6hJhamU9UkivKySd8Q.I3M8Bhk9hhLZ5cnDmEzFSTxEfg0qQSq")
+
+// htpasswdCheckInterval is the min duration between checks for modifications
+// of the htpasswd file.
+const htpasswdCheckInterval = 5 * time.Second
+
+// BasicAuthExtension installs the extension to authenticate requests with
+// htpasswd files.
+func BasicAuthExtension(config BasicAuthConfig) Extension {
+	return func(c *Core) (string, error) {
+		if config.HtpasswdFile == "" && len(config.Realms) == 0 {
+			return "basic auth: disabled", nil
+		}
+		auth, err := NewBasicAuth(config)
+		if err != nil {
+			return "basic auth: errored", err
+		}
+		c.ApplyAccess(auth.Authenticate)
+		return fmt.Sprintf("basic auth: %d realm(s)", len(auth.realms)), nil
+	}
+}
+
+// NewBasicAuth creates a new BasicAuth object.
+func NewBasicAuth(config BasicAuthConfig) (*BasicAuth, error) {
+	realms := config.Realms
+	if config.HtpasswdFile != "" {
+		realms = append(realms, BasicAuthRealm{
+			Prefix:       "/",
+			Realm:        config.Realm,
+			HtpasswdFile: config.HtpasswdFile})
+	}
+
+	auth := &BasicAuth{exempt: splitList(config.Exempt)}
+	for _, r := range realms {
+		passwords := &htpasswd{path: r.HtpasswdFile}
+		if err := passwords.load(); err != nil {
+			return nil, err
+		}
+		name := r.Realm
+		if name == "" {
+			name = "minio-web"
+		}
+		auth.realms = append(auth.realms, &realm{prefix: r.Prefix, name: name, htpasswd: passwords})
+	}
+	// longest prefix is matched first
+	sort.SliceStable(auth.realms, func(i, j int) bool {
+		return len(auth.realms[i].prefix) > len(auth.realms[j].prefix)
+	})
+	return auth, nil
+}
+
+// load reads the htpasswd file.
+func (h *htpasswd) load() error {
+	file, err := os.Open(h.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	users := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		users[parts[0]] = parts[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.users = users
+	h.modTime = info.ModTime()
+	h.checkedAt = time.Now()
+	return nil
+}
+
+// reloadIfModified reloads the htpasswd file if it has been modified.
+func (h *htpasswd) reloadIfModified() {
+	h.mutex.RLock()
+	checkedAt, modTime := h.checkedAt, h.modTime
+	h.mutex.RUnlock()
+	if time.Since(checkedAt) < htpasswdCheckInterval {
+		return
+	}
+
+	info, err := os.Stat(h.path)
+	if err != nil || info.ModTime().Equal(modTime) {
+		// keep the current users if the file is temporarily unavailable
+		h.mutex.Lock()
+		h.checkedAt = time.Now()
+		h.mutex.Unlock()
+		return
+	}
+	h.load()
+}
+
+// verify checks the password of the user.
+func (h *htpasswd) verify(user string, password string) bool {
+	h.reloadIfModified()
+
+	h.mutex.RLock()
+	hash, ok := h.users[user]
+	h.mutex.RUnlock()
+	if !ok {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(hash, "$2"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	case strings.HasPrefix(hash, "{SHA}"):
+		sum := sha1.Sum([]byte(password))
+		expected := base64.StdEncoding.EncodeToString(sum[:])
+		return subtle.ConstantTimeCompare([]byte(hash[5:]), []byte(expected)) == 1
+	default:
+		// unsupported hash (e.g. MD5 or crypt)
+		return false
+	}
+}
+
+// getRealm returns the realm protecting the url if any.
+func (a *BasicAuth) getRealm(url string) *realm {
+	for _, r := range a.realms {
+		if hasPathPrefix(url, r.prefix) {
+			return r
+		}
+	}
+	return nil
+}
+
+// Authenticate decorates a RequestHandler to require http basic authentication
+// for urls inside a realm.
+func (a *BasicAuth) Authenticate(handler RequestHandler) RequestHandler {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		url := r.URL.Path
+		protected := a.getRealm(url)
+		// requests can already be authenticated (e.g. signed urls)
+		_, authenticated := GetIdentity(r)
+		if protected == nil || authenticated || hasAnyPrefix(url, a.exempt) {
+			handler(w, r)
+			return
+		}
+
+		identity, ok := authenticate(r, "basic:"+protected.prefix, func() (Identity, bool) {
+			user, password, ok := r.BasicAuth()
+			if !ok || !protected.htpasswd.verify(user, password) {
+				return Identity{}, false
+			}
+			return Identity{Name: user, Provider: "basic"}, true
+		})
+		if !ok {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm="%s", charset="UTF-8"`, protected.name))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler(w, WithIdentity(r, identity))
+	}
+}
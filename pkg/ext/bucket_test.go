@@ -0,0 +1,116 @@
+package ext
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	mn "github.com/e2fyi/minio-web/pkg/minio"
+)
+
+// fakeModTime is the last modified date of the objects of the fake bucket,
+// plus one minute per byte of their content.
+var fakeModTime = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// newFakeBucket returns a minimal S3 server serving the objects (key and
+// content) of a bucket named "bucket".
+func newFakeBucket(t *testing.T, objects map[string]string) (*httptest.Server, *MinioHelper) {
+	var keys []string
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	modTime := func(key string) time.Time {
+		return fakeModTime.Add(time.Duration(len(objects[key])) * time.Minute)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if _, ok := query["location"]; ok {
+			fmt.Fprint(w, `<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`)
+			return
+		}
+		if query.Get("list-type") != "2" {
+			serveFakeObject(w, r, objects, modTime)
+			return
+		}
+
+		prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+		maxKeys, err := strconv.Atoi(query.Get("max-keys"))
+		if err != nil || maxKeys <= 0 {
+			maxKeys = 1000
+		}
+		// keys and folders in key order
+		var entries []string
+		seen := map[string]bool{}
+		for _, key := range keys {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			rest := key[len(prefix):]
+			if i := strings.Index(rest, delimiter); delimiter != "" && i >= 0 {
+				key = prefix + rest[:i+1]
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			if token := query.Get("continuation-token"); token == "" || key > token {
+				entries = append(entries, key)
+			}
+		}
+		truncated, next := false, ""
+		if len(entries) > maxKeys {
+			entries = entries[:maxKeys]
+			truncated, next = true, entries[maxKeys-1]
+		}
+
+		var contents, prefixes []string
+		for _, key := range entries {
+			if strings.HasSuffix(key, "/") && seen[key] {
+				prefixes = append(prefixes, "<CommonPrefixes><Prefix>"+key+"</Prefix></CommonPrefixes>")
+				continue
+			}
+			contents = append(contents, fmt.Sprintf(`<Contents><Key>%s</Key><LastModified>%s</LastModified><ETag>"%x"</ETag><Size>%d</Size></Contents>`,
+				key, modTime(key).Format(time.RFC3339), len(objects[key]), len(objects[key])))
+		}
+		fmt.Fprintf(w, `<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>bucket</Name><Prefix>%s</Prefix><KeyCount>%d</KeyCount><MaxKeys>%d</MaxKeys><Delimiter>%s</Delimiter><IsTruncated>%t</IsTruncated><NextContinuationToken>%s</NextContinuationToken>%s%s</ListBucketResult>`,
+			prefix, len(entries), maxKeys, delimiter, truncated, next, strings.Join(contents, ""), strings.Join(prefixes, ""))
+	}))
+	helper, err := mn.NewMinioHelperWithBucket(mn.Config{Endpoint: strings.TrimPrefix(server.URL, "http://"), AccessKey: "key", SecretKey: "secret"}, "bucket", "", 1)
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	return server, &helper
+}
+
+// serveFakeObject serves an object of the fake bucket (GET or HEAD).
+func serveFakeObject(w http.ResponseWriter, r *http.Request, objects map[string]string, modTime func(string) time.Time) {
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	content, ok := objects[key]
+	if !ok {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `<Error><Code>NoSuchKey</Code><Message>not found</Message><Key>%s</Key></Error>`, key)
+		return
+	}
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.Header().Set("ETag", fmt.Sprintf(`"%x"`, len(content)))
+	w.Header().Set("Last-Modified", modTime(key).Format(http.TimeFormat))
+	if r.Method == http.MethodGet {
+		fmt.Fprint(w, content)
+	}
+}
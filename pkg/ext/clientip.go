@@ -0,0 +1,119 @@
+package ext
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIPResolver resolves the ip of the client of a request. Forwarding
+// headers (Forwarded, X-Forwarded-For) are only trusted if the immediate peer
+// is a trusted proxy.
+type ClientIPResolver struct {
+	trusted []*net.IPNet
+}
+
+// NewClientIPResolver creates a new ClientIPResolver from a comma separated
+// list of trusted proxies (ips or CIDRs).
+func NewClientIPResolver(trustedProxies string) (*ClientIPResolver, error) {
+	trusted, err := parseCIDRs(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientIPResolver{trusted: trusted}, nil
+}
+
+// parseCIDRs parses a comma separated list of ips or CIDRs.
+func parseCIDRs(s string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, value := range splitList(s) {
+		if !strings.Contains(value, "/") {
+			if ip := net.ParseIP(value); ip != nil && ip.To4() != nil {
+				value += "/32"
+			} else {
+				value += "/128"
+			}
+		}
+		_, ipnet, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, ipnet)
+	}
+	return nets, nil
+}
+
+// containsIP checks whether the ip is inside any of the networks.
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, ipnet := range nets {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the ip of the client.
+func (c *ClientIPResolver) ClientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer := net.ParseIP(host)
+	if peer == nil || !containsIP(c.trusted, peer) {
+		return peer
+	}
+
+	// walk the forwarding chain from the nearest hop, the first untrusted hop
+	// is the client
+	hops := forwardedFor(r)
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hops[i])
+		if ip == nil {
+			return peer
+		}
+		if !containsIP(c.trusted, ip) || i == 0 {
+			return ip
+		}
+	}
+	return peer
+}
+
+// forwardedFor returns the forwarding chain (client first) from the Forwarded
+// or X-Forwarded-For headers.
+func forwardedFor(r *http.Request) []string {
+	var hops []string
+	if forwarded := r.Header.Values("Forwarded"); len(forwarded) > 0 {
+		for _, element := range strings.Split(strings.Join(forwarded, ","), ",") {
+			for _, pair := range strings.Split(element, ";") {
+				pair = strings.TrimSpace(pair)
+				if len(pair) < 4 || !strings.EqualFold(pair[:4], "for=") {
+					continue
+				}
+				hops = append(hops, parseForwardedNode(pair[4:]))
+			}
+		}
+		return hops
+	}
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// parseForwardedNode extracts the ip of a Forwarded node
+// (e.g. "[2001:db8::1]:4711" or 192.0.2.60).
+func parseForwardedNode(node string) string {
+	node = strings.Trim(node, `"`)
+	if strings.HasPrefix(node, "[") {
+		if end := strings.Index(node, "]"); end > 0 {
+			return node[1:end]
+		}
+	}
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return node
+}
@@ -0,0 +1,330 @@
+package ext
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bluele/gcache"
+	glob "github.com/gobwas/glob"
+)
+
+// CorsConfig is used to config the CORS extension.
+type CorsConfig struct {
+	// Handles CORS preflight requests and adds CORS headers if set.
+	Enabled bool `json:"enabled"`
+	// Comma separated list of allowed origins. An origin can be an exact
+	// match (https://abc.com), a wildcard (*, https://*.abc.com) or a regular
+	// expression prefixed with "~" which must match the whole origin (e.g.
+	// ~https://(a|b)\.abc\.com).
+	AllowedOrigins string `json:"allowedorigins"`
+	// Comma separated list of allowed methods (default: GET,HEAD).
+	AllowedMethods string `json:"allowedmethods"`
+	// Comma separated list of allowed request headers (wildcards allowed).
+	AllowedHeaders string `json:"allowedheaders"`
+	// Comma separated list of response headers exposed to the browser.
+	ExposedHeaders string `json:"exposedheaders"`
+	// Number of seconds a preflight response can be cached.
+	MaxAge int `json:"maxage"`
+	// Allows requests with credentials (cookies, authorization headers).
+	// Cannot be used with the "*" origin, and is never allowed for the
+	// bucket rules with the "*" origin.
+	AllowCredentials bool `json:"allowcredentials"`
+	// Derives additional rules from the S3 CORS configuration of the bucket.
+	FromBucket bool `json:"frombucket"`
+}
+
+// Cors provides the decorator to handle cross-origin requests.
+type Cors struct {
+	rules       []corsRule
+	helper      *MinioHelper
+	credentials bool
+	// cached cors rules for each bucket
+	bucketRules gcache.Cache
+}
+
+// corsRule describes which cross-origin requests are allowed.
+type corsRule struct {
+	origins     []matcher
+	methods     []string
+	headers     []matcher
+	exposed     []string
+	maxAge      int
+	credentials bool
+}
+
+// matcher checks whether a value matches an expression.
+type matcher = func(value string) bool
+
+// s3CorsConfiguration is the S3 CORS configuration of a bucket.
+type s3CorsConfiguration struct {
+	Rules []struct {
+		AllowedOrigins []string `xml:"AllowedOrigin"`
+		AllowedMethods []string `xml:"AllowedMethod"`
+		AllowedHeaders []string `xml:"AllowedHeader"`
+		ExposeHeaders  []string `xml:"ExposeHeader"`
+		MaxAgeSeconds  int      `xml:"MaxAgeSeconds"`
+	} `xml:"CORSRule"`
+}
+
+// CorsExtension installs the extension to handle CORS preflight requests and
+// add the CORS headers to the responses.
+func CorsExtension(helper *MinioHelper, config CorsConfig) Extension {
+	return func(c *Core) (string, error) {
+		if !config.Enabled {
+			return "cors: disabled", nil
+		}
+		cors, err := NewCors(helper, config)
+		if err != nil {
+			return "cors: errored", err
+		}
+		c.ApplyRequest(cors.HandleCors)
+		return fmt.Sprintf("cors: %s", config.AllowedOrigins), nil
+	}
+}
+
+// NewCors creates a new Cors object.
+func NewCors(helper *MinioHelper, config CorsConfig) (*Cors, error) {
+	cors := &Cors{helper: helper, credentials: config.AllowCredentials}
+
+	origins := splitList(config.AllowedOrigins)
+	if config.AllowCredentials && anyOrigin(origins) {
+		return nil, fmt.Errorf("cors: allowed origin * cannot be used with credentials")
+	}
+	if len(origins) > 0 {
+		methods := splitList(config.AllowedMethods)
+		if len(methods) == 0 {
+			methods = []string{"GET", "HEAD"}
+		}
+		rule, err := newCorsRule(origins, methods, splitList(config.AllowedHeaders))
+		if err != nil {
+			return nil, err
+		}
+		rule.exposed = splitList(config.ExposedHeaders)
+		rule.maxAge = config.MaxAge
+		rule.credentials = config.AllowCredentials
+		cors.rules = append(cors.rules, rule)
+	}
+
+	if config.FromBucket {
+		cors.bucketRules = gcache.New(100).
+			ARC().
+			Expiration(5 * time.Minute).
+			LoaderFunc(cors.loadBucketRules).
+			Build()
+	}
+	return cors, nil
+}
+
+// newCorsRule creates a new corsRule from lists of expressions.
+func newCorsRule(origins []string, methods []string, headers []string) (corsRule, error) {
+	rule := corsRule{}
+	for _, origin := range origins {
+		m, err := newMatcher(origin, true)
+		if err != nil {
+			return rule, err
+		}
+		rule.origins = append(rule.origins, m)
+	}
+	for _, method := range methods {
+		rule.methods = append(rule.methods, strings.ToUpper(method))
+	}
+	for _, header := range headers {
+		m, err := newMatcher(strings.ToLower(header), false)
+		if err != nil {
+			return rule, err
+		}
+		rule.headers = append(rule.headers, m)
+	}
+	return rule, nil
+}
+
+// anyOrigin checks whether any origin is allowed (i.e. "*").
+func anyOrigin(origins []string) bool {
+	for _, origin := range origins {
+		if strings.TrimSpace(origin) == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// newMatcher creates a matcher for an exact, wildcard or regular expression.
+func newMatcher(expr string, allowRegexp bool) (matcher, error) {
+	switch {
+	case expr == "*":
+		return func(string) bool { return true }, nil
+	case allowRegexp && strings.HasPrefix(expr, "~"):
+		// anchored so that the expression matches the whole value
+		re, err := regexp.Compile("^(?:" + expr[1:] + ")$")
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	case strings.Contains(expr, "*"):
+		g, err := glob.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		return g.Match, nil
+	default:
+		return func(value string) bool { return strings.EqualFold(value, expr) }, nil
+	}
+}
+
+// loadBucketRules retrieves and parses the S3 CORS configuration of a bucket.
+func (c *Cors) loadBucketRules(key interface{}) (interface{}, error) {
+	rules := []corsRule{}
+	data, err := c.helper.GetBucketCors(key.(string))
+	// buckets without a readable configuration are cached as having no rules
+	if err != nil || len(data) == 0 {
+		return rules, nil
+	}
+	var config s3CorsConfiguration
+	if err := xml.Unmarshal(data, &config); err != nil {
+		return rules, nil
+	}
+	for _, s3Rule := range config.Rules {
+		rule, err := newCorsRule(s3Rule.AllowedOrigins, s3Rule.AllowedMethods, s3Rule.AllowedHeaders)
+		if err != nil {
+			continue
+		}
+		rule.exposed = s3Rule.ExposeHeaders
+		rule.maxAge = s3Rule.MaxAgeSeconds
+		// any origin must not be allowed to make requests with credentials
+		rule.credentials = c.credentials && !anyOrigin(s3Rule.AllowedOrigins)
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// getRules returns the rules applicable to the requested url.
+func (c *Cors) getRules(url string) []corsRule {
+	if c.bucketRules == nil || c.helper == nil {
+		return c.rules
+	}
+	bucketName, _ := c.helper.GetBucketNameAndPrefix(url)
+	if bucketName == "" {
+		return c.rules
+	}
+	unknown, err := c.bucketRules.Get(bucketName)
+	if err != nil {
+		return c.rules
+	}
+	return append(append([]corsRule{}, c.rules...), unknown.([]corsRule)...)
+}
+
+// findRule returns the first rule which allows the origin and method.
+func (c *Cors) findRule(url string, origin string, method string) *corsRule {
+	rules := c.getRules(url)
+	for i := range rules {
+		if rules[i].allowsOrigin(origin) && rules[i].allowsMethod(method) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// allowsOrigin checks whether the origin is allowed by the rule.
+func (rule *corsRule) allowsOrigin(origin string) bool {
+	for _, match := range rule.origins {
+		if match(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsMethod checks whether the method is allowed by the rule.
+func (rule *corsRule) allowsMethod(method string) bool {
+	for _, allowed := range rule.methods {
+		if allowed == method {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsHeaders checks whether all the request headers are allowed by the
+// rule.
+func (rule *corsRule) allowsHeaders(headers []string) bool {
+	for _, header := range headers {
+		allowed := false
+		for _, match := range rule.headers {
+			if match(strings.ToLower(header)) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// HandleCors decorates a RequestHandler to handle preflight requests and add
+// CORS headers to cross-origin requests.
+func (c *Cors) HandleCors(handler RequestHandler) RequestHandler {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			handler(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+
+		method := r.Method
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			method = strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		rule := c.findRule(r.URL.Path, origin, method)
+		if rule == nil {
+			if preflight {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			handler(w, r)
+			return
+		}
+
+		if !preflight {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if rule.credentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+			if len(rule.exposed) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(rule.exposed, ", "))
+			}
+			handler(w, r)
+			return
+		}
+
+		headers := splitList(r.Header.Get("Access-Control-Request-Headers"))
+		if !rule.allowsHeaders(headers) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if rule.credentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(rule.methods, ", "))
+		if len(headers) > 0 {
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+		}
+		if rule.maxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(rule.maxAge))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,29 @@
+package ext
+
+import "testing"
+
+func TestOriginMatcher(t *testing.T) {
+	tests := []struct {
+		expr    string
+		origin  string
+		matches bool
+	}{
+		{"https://a.com", "https://a.com", true},
+		{"https://a.com", "https://a.com.evil.net", false},
+		{"https://*.a.com", "https://b.a.com", true},
+		{"https://*.a.com", "https://b.evil.net", false},
+		{`~https://(a|b)\.com`, "https://b.com", true},
+		{`~https://a\.com`, "https://a.com.evil.net", false},
+		{`~https://a\.com`, "https://evil.net/https://a.com", false},
+		{`~^https://a\.com$`, "https://a.com", true},
+	}
+	for _, test := range tests {
+		match, err := newMatcher(test.expr, true)
+		if err != nil {
+			t.Fatalf("%s: %v", test.expr, err)
+		}
+		if match(test.origin) != test.matches {
+			t.Errorf("%s with %s: expected %t", test.expr, test.origin, test.matches)
+		}
+	}
+}
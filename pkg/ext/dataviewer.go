@@ -0,0 +1,568 @@
+package ext
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	humanize "github.com/dustin/go-humanize"
+)
+
+// dataViewerTemplate is the default template of the data viewer.
+const dataViewerTemplate = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8" />
+  <meta name="viewport" content="width=device-width, initial-scale=1" />
+  <title>{{.Name}}</title>
+  <style>
+    body {
+      color: #24292e;
+      font-family: -apple-system, BlinkMacSystemFont, Segoe UI, Helvetica, Arial,
+        sans-serif;
+      margin: 0 auto;
+      max-width: 1280px;
+      padding: 45px;
+    }
+    a {
+      color: #0366d6;
+      text-decoration: none;
+    }
+    header {
+      align-items: baseline;
+      border-bottom: 1px solid #eaecef;
+      display: flex;
+      justify-content: space-between;
+      margin-bottom: 16px;
+    }
+    .table {
+      overflow-x: auto;
+    }
+    table {
+      border-collapse: collapse;
+      font-size: 14px;
+      width: 100%;
+    }
+    th,
+    td {
+      border: 1px solid #dfe2e5;
+      padding: 6px 13px;
+      text-align: left;
+    }
+    th {
+      background-color: #f6f8fa;
+      cursor: pointer;
+      user-select: none;
+    }
+    th[data-order="asc"]::after {
+      content: " \25B2";
+    }
+    th[data-order="desc"]::after {
+      content: " \25BC";
+    }
+    nav {
+      margin-top: 16px;
+      text-align: center;
+    }
+    .tree,
+    .tree ul {
+      font-family: SFMono-Regular, Consolas, Liberation Mono, Menlo, monospace;
+      font-size: 12px;
+      line-height: 20px;
+      list-style: none;
+      margin: 0;
+      padding-left: 20px;
+    }
+    .tree summary {
+      cursor: pointer;
+    }
+    .tree details:not([open]) > summary::after {
+      content: "…";
+    }
+    .json-count {
+      color: #6a737d;
+      margin: 0 6px;
+    }
+    .json-key {
+      color: #005cc5;
+    }
+    .json-string {
+      color: #032f62;
+    }
+    .json-number,
+    .json-boolean,
+    .json-null {
+      color: #d73a49;
+    }
+    .truncated {
+      color: #6a737d;
+    }
+  </style>
+</head>
+<body>
+  <header>
+    <h2>{{.Name}}</h2>
+    <span>{{if not .Tree}}{{len .Rows}} rows · {{end}}{{.HumanSize}} · <a href="{{.RawURL}}">Download</a></span>
+  </header>
+  {{- if .Tree}}
+  <ul class="tree">{{.Tree}}</ul>
+  {{- else}}
+  <div class="table">
+    <table>
+      <thead>
+        <tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+      </thead>
+      <tbody>
+        {{- range .Rows}}
+        <tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+        {{- end}}
+      </tbody>
+    </table>
+  </div>
+  <nav>
+    <button id="previous">Previous</button>
+    <span id="page"></span>
+    <button id="next">Next</button>
+  </nav>
+  {{- end}}
+  {{- if .Truncated}}
+  <p class="truncated">The rows after the first {{len .Rows}} are not shown, download the file to see them all.</p>
+  {{- end}}
+  {{- if not .Tree}}
+  <script>
+    (function () {
+      var pageSize = {{.PageSize}};
+      var tbody = document.querySelector("tbody");
+      var rows = Array.prototype.slice.call(tbody.rows);
+      var pages = Math.max(1, Math.ceil(rows.length / pageSize));
+      var page = 0;
+
+      function show() {
+        rows.forEach(function (row, i) {
+          row.style.display = Math.floor(i / pageSize) === page ? "" : "none";
+        });
+        document.getElementById("page").textContent = "Page " + (page + 1) + " of " + pages;
+        document.getElementById("previous").disabled = page === 0;
+        document.getElementById("next").disabled = page === pages - 1;
+      }
+
+      function compare(a, b) {
+        var x = parseFloat(a), y = parseFloat(b);
+        if (!isNaN(x) && !isNaN(y) && String(x) === a.trim() && String(y) === b.trim()) {
+          return x - y;
+        }
+        return a.localeCompare(b);
+      }
+
+      document.querySelectorAll("th").forEach(function (th, column) {
+        th.addEventListener("click", function () {
+          var order = th.dataset.order === "asc" ? "desc" : "asc";
+          document.querySelectorAll("th").forEach(function (other) {
+            delete other.dataset.order;
+          });
+          th.dataset.order = order;
+          rows.sort(function (a, b) {
+            var x = a.cells[column] ? a.cells[column].textContent : "";
+            var y = b.cells[column] ? b.cells[column].textContent : "";
+            return order === "asc" ? compare(x, y) : compare(y, x);
+          });
+          rows.forEach(function (row) {
+            tbody.appendChild(row);
+          });
+          page = 0;
+          show();
+        });
+      });
+      document.getElementById("previous").addEventListener("click", function () {
+        page--;
+        show();
+      });
+      document.getElementById("next").addEventListener("click", function () {
+        page++;
+        show();
+      });
+      show();
+    })();
+  </script>
+  {{- end}}
+</body>
+</html>
+`
+
+// DataViewerConfig is used to config the data viewer, which renders CSV/TSV
+// objects as paginated sortable tables and JSON objects as collapsible trees.
+// The original object is downloaded with ?raw=1.
+type DataViewerConfig struct {
+	// Comma separated list of extensions of the objects rendered (csv, tsv
+	// and/or json).
+	Extensions string `json:"extensions"`
+	// Comma separated list of content types of the objects rendered (e.g.
+	// text/csv,application/json). Disabled if no extensions nor content types.
+	ContentTypes string `json:"contenttypes"`
+	// Max number of rows of the tables, the next rows are not rendered
+	// (default: 1000).
+	MaxRows int `json:"maxrows"`
+	// Number of rows per page of the tables (default: 50).
+	PageSize int `json:"pagesize"`
+	// JSON objects larger than the size (bytes) are returned as is (default:
+	// 5 MB).
+	MaxSize int64 `json:"maxsize"`
+	// Path to a html/template to render the data (default: built-in).
+	Template string `json:"template"`
+}
+
+// DataViewData provides the view to the HTML template of the data viewer.
+type DataViewData struct {
+	// name of the object (e.g. data.csv)
+	Name string
+	Key  string
+	// url of the original object
+	RawURL string
+	Size   int64
+	// header and rows of the CSV/TSV objects
+	Header []string
+	Rows   [][]string
+	// true if the rows after the max rows are not rendered
+	Truncated bool
+	PageSize  int
+	// collapsible tree of the JSON objects
+	Tree template.HTML
+}
+
+// HumanSize returns the size of the object in a human readable format.
+func (data DataViewData) HumanSize() string {
+	return humanize.Bytes(uint64(data.Size))
+}
+
+// dataFormats are the formats rendered by the data viewer.
+var dataFormats = map[string]bool{"csv": true, "tsv": true, "json": true}
+
+// DataViewer provides the decorator to render data objects as HTML.
+type DataViewer struct {
+	// formats by extension and content type
+	extensions   map[string]string
+	contentTypes map[string]string
+	maxRows      int
+	pageSize     int
+	maxSize      int64
+	template     *template.Template
+}
+
+// DataViewerExtension installs the data viewer if extensions or content types
+// are provided.
+func DataViewerExtension(config DataViewerConfig) Extension {
+	return func(c *Core) (string, error) {
+		if config.Extensions == "" && config.ContentTypes == "" {
+			return "data viewer: disabled", nil
+		}
+		viewer, err := NewDataViewer(config)
+		if err != nil {
+			return "data viewer: errored", err
+		}
+		c.ApplyServe(viewer.RenderData)
+		c.ApplyRequest(viewer.HandleRaw)
+		return fmt.Sprintf("data viewer: %s", strings.Trim(config.Extensions+","+config.ContentTypes, ",")), nil
+	}
+}
+
+// NewDataViewer creates a new DataViewer object.
+func NewDataViewer(config DataViewerConfig) (*DataViewer, error) {
+	viewer := &DataViewer{
+		extensions:   map[string]string{},
+		contentTypes: map[string]string{},
+		maxRows:      config.MaxRows,
+		pageSize:     config.PageSize,
+		maxSize:      config.MaxSize}
+	for _, extension := range strings.Split(config.Extensions, ",") {
+		extension = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(extension)), ".")
+		if extension == "" {
+			continue
+		}
+		if !dataFormats[extension] {
+			return nil, fmt.Errorf("unsupported data extension: %s", extension)
+		}
+		viewer.extensions[extension] = extension
+	}
+	for _, contentType := range strings.Split(config.ContentTypes, ",") {
+		contentType = strings.ToLower(strings.TrimSpace(contentType))
+		switch {
+		case contentType == "":
+			continue
+		case strings.Contains(contentType, "tab-separated"):
+			viewer.contentTypes[contentType] = "tsv"
+		case strings.Contains(contentType, "csv"):
+			viewer.contentTypes[contentType] = "csv"
+		case strings.Contains(contentType, "json"):
+			viewer.contentTypes[contentType] = "json"
+		default:
+			return nil, fmt.Errorf("unsupported data content type: %s", contentType)
+		}
+	}
+
+	var err error
+	if config.Template != "" {
+		viewer.template, err = template.ParseFiles(config.Template)
+	} else {
+		viewer.template, err = template.New("data").Parse(dataViewerTemplate)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if viewer.maxRows <= 0 {
+		viewer.maxRows = 1000
+	}
+	if viewer.pageSize <= 0 {
+		viewer.pageSize = 50
+	}
+	if viewer.maxSize <= 0 {
+		viewer.maxSize = 5 * 1024 * 1024
+	}
+	return viewer, nil
+}
+
+// format returns the format of a resource (csv, tsv or json), or an empty
+// string if it is not rendered.
+func (v *DataViewer) format(resource Resource) string {
+	extension := strings.TrimPrefix(strings.ToLower(path.Ext(resource.Info.Key)), ".")
+	if format, ok := v.extensions[extension]; ok {
+		return format
+	}
+	mediaType, _, err := mime.ParseMediaType(resource.Info.ContentType)
+	if err != nil {
+		return ""
+	}
+	return v.contentTypes[mediaType]
+}
+
+// readTable reads the header and the rows of a CSV/TSV up to the max rows. The
+// rows after an invalid row are not rendered either.
+func (v *DataViewer) readTable(data io.Reader, comma rune, view *DataViewData) {
+	reader := csv.NewReader(bufio.NewReader(data))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	view.Header = []string{}
+	for i := 0; ; i++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return
+		}
+		if err != nil || len(view.Rows) == v.maxRows {
+			view.Truncated = true
+			return
+		}
+		if i == 0 {
+			view.Header = record
+			continue
+		}
+		view.Rows = append(view.Rows, record)
+	}
+}
+
+// writeJSONValue writes a JSON value other than an object or an array.
+func writeJSONValue(buf *bytes.Buffer, token json.Token) {
+	switch value := token.(type) {
+	case string:
+		fmt.Fprintf(buf, `<span class="json-string">%s</span>`, template.HTMLEscapeString(strconv.Quote(value)))
+	case json.Number:
+		fmt.Fprintf(buf, `<span class="json-number">%s</span>`, value)
+	case bool:
+		fmt.Fprintf(buf, `<span class="json-boolean">%t</span>`, value)
+	case nil:
+		buf.WriteString(`<span class="json-null">null</span>`)
+	}
+}
+
+// writeJSONTree writes the next JSON value of the decoder as a tree, whose
+// objects and arrays are collapsible (and collapsed below the second level).
+func writeJSONTree(buf *bytes.Buffer, dec *json.Decoder, depth int) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		writeJSONValue(buf, token)
+		return nil
+	}
+
+	closing, unit := "}", "keys"
+	if delim == '[' {
+		closing, unit = "]", "items"
+	}
+	var children bytes.Buffer
+	count := 0
+	for dec.More() {
+		children.WriteString("<li>")
+		if delim == '{' {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(&children, `<span class="json-key">%s</span>: `, template.HTMLEscapeString(strconv.Quote(fmt.Sprint(key))))
+		}
+		if err := writeJSONTree(&children, dec, depth+1); err != nil {
+			return err
+		}
+		children.WriteString("</li>")
+		count++
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+
+	if count == 0 {
+		fmt.Fprintf(buf, "%s%s", delim, closing)
+		return nil
+	}
+	open := ""
+	if depth < 2 {
+		open = " open"
+	}
+	fmt.Fprintf(buf, `<details%s><summary>%s<span class="json-count">%d %s</span></summary><ul>%s</ul>%s</details>`,
+		open, delim, count, unit, children.Bytes(), closing)
+	return nil
+}
+
+// readTree reads the JSON values (e.g. JSON lines) of an object as a tree.
+func readTree(content []byte, view *DataViewData) error {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	var tree bytes.Buffer
+	for {
+		var value bytes.Buffer
+		err := writeJSONTree(&value, dec, 0)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&tree, "<li>%s</li>", value.Bytes())
+	}
+	if tree.Len() == 0 {
+		return io.ErrUnexpectedEOF
+	}
+	view.Tree = template.HTML(tree.String())
+	return nil
+}
+
+// render renders a data object of a format as HTML. False is returned if the
+// object is not valid.
+func (v *DataViewer) render(resource Resource, format string) ([]byte, bool, error) {
+	view := DataViewData{
+		Name:     path.Base(resource.Info.Key),
+		Key:      resource.Info.Key,
+		RawURL:   "?raw=1",
+		Size:     resource.Info.Size,
+		PageSize: v.pageSize}
+	if format == "json" {
+		content, err := ioutil.ReadAll(resource.Data)
+		if err != nil || readTree(content, &view) != nil {
+			return nil, false, nil
+		}
+	} else {
+		comma := ','
+		if format == "tsv" {
+			comma = '\t'
+		}
+		v.readTable(resource.Data, comma, &view)
+	}
+
+	var rendered bytes.Buffer
+	err := v.template.Execute(&rendered, view)
+	return rendered.Bytes(), true, err
+}
+
+// rawWriter marks the responses of the requests downloading the original
+// objects (?raw=1), as the serve decorator only sees the response.
+type rawWriter struct {
+	http.ResponseWriter
+}
+
+// isRawWriter checks whether a response (or the response it wraps, e.g.
+// throttled) downloads the original object.
+func isRawWriter(w http.ResponseWriter) bool {
+	for {
+		switch writer := w.(type) {
+		case *rawWriter:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = writer.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// RenderData decorates a Serve function to render and return a HTML resource
+// from a CSV/TSV or JSON resource, unless it is downloaded (see HandleRaw).
+func (v *DataViewer) RenderData(Serve ServeHandler) ServeHandler {
+
+	return func(w http.ResponseWriter, resource Resource) error {
+		format := v.format(resource)
+		if isRawWriter(w) {
+			// only the data objects are downloaded as attachments
+			if format != "" {
+				w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(resource.Info.Key)}))
+			}
+			return Serve(w, resource)
+		}
+		if format == "" {
+			return Serve(w, resource)
+		}
+		if format == "json" {
+			// the large or invalid JSON objects are returned as is
+			if resource.Info.Size > v.maxSize {
+				return Serve(w, resource)
+			}
+			content, err := ioutil.ReadAll(resource.Data)
+			resource.Data = bytes.NewReader(content)
+			if err != nil {
+				return Serve(w, resource)
+			}
+			rendered, ok, err := v.render(Resource{Info: resource.Info, Data: bytes.NewReader(content)}, format)
+			if !ok {
+				return Serve(w, resource)
+			}
+			return v.write(w, rendered, err)
+		}
+
+		rendered, _, err := v.render(resource, format)
+		return v.write(w, rendered, err)
+	}
+}
+
+// write writes a rendered data object as HTML.
+func (v *DataViewer) write(w http.ResponseWriter, rendered []byte, err error) error {
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.FormatInt(int64(len(rendered)), 10))
+	_, err = w.Write(rendered)
+	return err
+}
+
+// HandleRaw decorates a RequestHandler to download the original objects
+// requested with ?raw=1. The response is marked for the serve decorator, which
+// only adds the Content-Disposition header when a data object is served.
+func (v *DataViewer) HandleRaw(handler RequestHandler) RequestHandler {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && isRaw(r) && !strings.HasSuffix(r.URL.Path, "/") {
+			w = &rawWriter{w}
+		}
+		handler(w, r)
+	}
+}
@@ -0,0 +1,123 @@
+package ext
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/e2fyi/minio-web/pkg/core"
+)
+
+func TestDataViewer(t *testing.T) {
+	server, helper := newFakeBucket(t, map[string]string{
+		"data.csv":  "name,size\na,1\nb,2\nc,3\nd,4\n",
+		"data.tsv":  "name\tsize\na,b\t1\n",
+		"data.json": `{"a": [1, "x<y", true, null], "b": {}}`,
+		"big.json":  `{"a": "` + strings.Repeat("a", 100) + `"}`,
+		"notes.txt": "notes",
+	})
+	defer server.Close()
+
+	c := core.NewCore()
+	c.ChainStatObject(helper.StatObject)
+	c.ChainGetObject(helper.GetObject)
+	c.ApplyExtension(DataViewerExtension(DataViewerConfig{Extensions: "csv,tsv,json", MaxRows: 3, MaxSize: 100}))
+	c.Init()
+	handler := c.Handler()
+
+	tests := []struct {
+		url         string
+		status      int
+		disposition string
+		contains    []string
+		excludes    []string
+	}{
+		{
+			url:      "/data.csv",
+			status:   http.StatusOK,
+			contains: []string{"<th>name</th><th>size</th>", "<td>a</td><td>1</td>", "<td>c</td><td>3</td>", "3 rows", "The rows after the first 3 are not shown"},
+			excludes: []string{"<td>d</td>"},
+		},
+		{
+			url:      "/data.tsv",
+			status:   http.StatusOK,
+			contains: []string{"<th>name</th><th>size</th>", "<td>a,b</td><td>1</td>", "1 rows"},
+			excludes: []string{"not shown"},
+		},
+		{
+			url:    "/data.json",
+			status: http.StatusOK,
+			contains: []string{
+				`<details open><summary>{<span class="json-count">2 keys</span></summary>`,
+				`<span class="json-key">&#34;a&#34;</span>: <details open><summary>[<span class="json-count">4 items</span></summary>`,
+				`<li><span class="json-number">1</span></li>`,
+				`<li><span class="json-string">&#34;x&lt;y&#34;</span></li>`,
+				`<li><span class="json-boolean">true</span></li>`,
+				`<li><span class="json-null">null</span></li>`,
+				`<span class="json-key">&#34;b&#34;</span>: {}`,
+			},
+		},
+		{
+			url:      "/big.json",
+			status:   http.StatusOK,
+			contains: []string{`{"a": "aaaa`},
+			excludes: []string{"<html>"},
+		},
+		{
+			url:         "/data.csv?raw=1",
+			status:      http.StatusOK,
+			disposition: `attachment; filename=data.csv`,
+			contains:    []string{"name,size\na,1\nb,2\nc,3\nd,4\n"},
+		},
+		{
+			url:      "/notes.txt?raw=1",
+			status:   http.StatusOK,
+			contains: []string{"notes"},
+		},
+		{
+			url:    "/missing.csv?raw=1",
+			status: http.StatusNotFound,
+		},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, test.url, nil))
+		if w.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d", test.url, test.status, w.Code)
+		}
+		if disposition := w.Header().Get("Content-Disposition"); disposition != test.disposition {
+			t.Errorf("%s: expected Content-Disposition %q, got %q", test.url, test.disposition, disposition)
+		}
+		body := w.Body.String()
+		for _, s := range test.contains {
+			if !strings.Contains(body, s) {
+				t.Errorf("%s: expected %q in %q", test.url, s, body)
+			}
+		}
+		for _, s := range test.excludes {
+			if strings.Contains(body, s) {
+				t.Errorf("%s: unexpected %q in %q", test.url, s, body)
+			}
+		}
+	}
+}
+
+func TestDataViewerRawThrottled(t *testing.T) {
+	server, helper := newFakeBucket(t, map[string]string{"data.csv": "name,size\na,1\n"})
+	defer server.Close()
+
+	c := core.NewCore()
+	c.ChainStatObject(helper.StatObject)
+	c.ChainGetObject(helper.GetObject)
+	c.ApplyExtension(DataViewerExtension(DataViewerConfig{Extensions: "csv"}))
+	c.ApplyExtension(ThrottleExtension(helper, ThrottleConfig{Global: 1024 * 1024}))
+	c.Init()
+
+	// the serve decorator sees the throttled response
+	w := httptest.NewRecorder()
+	c.Handler()(w, httptest.NewRequest(http.MethodGet, "/data.csv?raw=1", nil))
+	if disposition := w.Header().Get("Content-Disposition"); w.Code != http.StatusOK || disposition != "attachment; filename=data.csv" {
+		t.Errorf("expected the download of the object, got %d %q", w.Code, disposition)
+	}
+}
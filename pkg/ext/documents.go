@@ -0,0 +1,185 @@
+package ext
+
+import (
+	"mime"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// DocumentRenderer converts a document (e.g. AsciiDoc) to markdown, which is
+// rendered with the markdown template, i.e. with its layouts, table of
+// contents and fenced blocks.
+type DocumentRenderer = func(content []byte) ([]byte, error)
+
+// documentRenderers are the renderers of the documents by extension (e.g.
+// .adoc) and content type (e.g. text/asciidoc).
+var documentRenderers = map[string]DocumentRenderer{
+	".md":                      markdownDocument,
+	".markdown":                markdownDocument,
+	"text/markdown":            markdownDocument,
+	".adoc":                    asciidocToMarkdown,
+	".asciidoc":                asciidocToMarkdown,
+	".asc":                     asciidocToMarkdown,
+	"text/asciidoc":            asciidocToMarkdown,
+	"text/x-asciidoc":          asciidocToMarkdown,
+	".rst":                     rstToMarkdown,
+	".rest":                    rstToMarkdown,
+	"text/x-rst":               rstToMarkdown,
+	"text/prs.fallenstein.rst": rstToMarkdown,
+}
+
+// RegisterDocumentRenderer registers a renderer for the extensions (e.g.
+// .org) and content types (e.g. text/org) of the documents, which overrides
+// the built-in renderers.
+func RegisterDocumentRenderer(renderer DocumentRenderer, keys ...string) {
+	for _, key := range keys {
+		documentRenderers[strings.ToLower(key)] = renderer
+	}
+}
+
+// markdownDocument renders a markdown as is.
+func markdownDocument(content []byte) ([]byte, error) {
+	return content, nil
+}
+
+// documentRenderer returns the renderer of a resource by the extension of its
+// key, then by its content type, or nil if it is not a document.
+func documentRenderer(resource Resource) DocumentRenderer {
+	if renderer, ok := documentRenderers[strings.ToLower(path.Ext(resource.Info.Key))]; ok {
+		return renderer
+	}
+	mediaType, _, err := mime.ParseMediaType(resource.Info.ContentType)
+	if err != nil {
+		return nil
+	}
+	if renderer, ok := documentRenderers[mediaType]; ok {
+		return renderer
+	}
+	// the content types of the markdowns vary (e.g. text/x-markdown)
+	if strings.Contains(mediaType, "markdown") {
+		return markdownDocument
+	}
+	return nil
+}
+
+// isDocument checks whether a resource is rendered as a document.
+func isDocument(resource Resource) bool {
+	return documentRenderer(resource) != nil
+}
+
+// replaceInline converts the inline markup of a line to markdown, i.e. the
+// matches of the pattern with the match function, and the text between them
+// with the text function.
+func replaceInline(line string, pattern *regexp.Regexp, match func(groups []string) string, text func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range pattern.FindAllStringSubmatchIndex(line, -1) {
+		b.WriteString(text(line[last:loc[0]]))
+		groups := make([]string, len(loc)/2)
+		for i := range groups {
+			if loc[2*i] >= 0 {
+				groups[i] = line[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		b.WriteString(match(groups))
+		last = loc[1]
+	}
+	b.WriteString(text(line[last:]))
+	return b.String()
+}
+
+// escapeHTML escapes the tags in the text of the converted documents, which
+// would be rendered as HTML in markdown.
+func escapeHTML(text string) string {
+	return strings.ReplaceAll(text, "<", "&lt;")
+}
+
+// codeFence returns a fenced code block of a language.
+func codeFence(language string, lines []string) []string {
+	fence := "```"
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fence = "~~~~"
+			break
+		}
+	}
+	block := append([]string{"", fence + language}, lines...)
+	return append(block, fence, "")
+}
+
+// quote returns the lines of a block quote, with an optional label (e.g. Note).
+func quote(label string, lines []string) []string {
+	var block []string
+	if label != "" {
+		block = append(block, "> **"+label+"**", ">")
+	}
+	for _, line := range lines {
+		block = append(block, strings.TrimRight("> "+line, " "))
+	}
+	return append(block, "")
+}
+
+// markdownTable returns a markdown table of the rows, whose first row is the
+// header.
+func markdownTable(rows [][]string) []string {
+	if len(rows) == 0 {
+		return nil
+	}
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	format := func(row []string) string {
+		cells := make([]string, columns)
+		for i := range cells {
+			if i < len(row) {
+				cells[i] = strings.ReplaceAll(row[i], "|", `\|`)
+			}
+		}
+		return "| " + strings.Join(cells, " | ") + " |"
+	}
+	table := []string{"", format(rows[0]), "|" + strings.Repeat(" --- |", columns)}
+	for _, row := range rows[1:] {
+		table = append(table, format(row))
+	}
+	return append(table, "")
+}
+
+// dedent removes the common indentation of the lines and the leading and
+// trailing blank lines.
+func dedent(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if n := len(line) - len(strings.TrimLeft(line, " \t")); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	dedented := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			line = line[indent:]
+		}
+		if strings.TrimSpace(line) != "" {
+			dedented[i] = line
+		}
+	}
+	return dedented
+}
+
+// splitLines splits a document into lines.
+func splitLines(content []byte) []string {
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	return strings.Split(strings.ReplaceAll(text, "\t", "    "), "\n")
+}
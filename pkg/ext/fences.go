@@ -0,0 +1,182 @@
+package ext
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/alecthomas/chroma/lexers"
+	"gitlab.com/golang-commonmark/markdown"
+)
+
+// FenceRenderer renders the content of a fenced block as HTML, and returns the
+// HTML of the assets (e.g. scripts) required by the rendered block. The assets
+// are added once to the page.
+type FenceRenderer = func(content string) (html string, assets []string)
+
+// fenceRenderers are the renderers registered for the languages of the fenced
+// blocks.
+var fenceRenderers = map[string]FenceRenderer{}
+
+// RegisterFenceRenderer registers a renderer for the fenced blocks of the
+// languages (e.g. ```dot), which overrides the built-in renderers. The fenced
+// blocks without a renderer are highlighted.
+func RegisterFenceRenderer(renderer FenceRenderer, languages ...string) {
+	for _, language := range languages {
+		fenceRenderers[strings.ToLower(language)] = renderer
+	}
+}
+
+// katexAssets renders the .math blocks with KaTeX once loaded.
+const katexAssets = `<link rel="stylesheet" href="%[1]s/katex.min.css" />
+<script defer src="%[1]s/katex.min.js"></script>
+<script>
+  document.addEventListener("DOMContentLoaded", function () {
+    document.querySelectorAll(".math").forEach(function (el) {
+      katex.render(el.textContent, el, { displayMode: true, throwOnError: false });
+    });
+  });
+</script>
+`
+
+// mermaidAssets renders the .mermaid blocks with mermaid once loaded.
+const mermaidAssets = `<script type="module">
+  import mermaid from "%s";
+  mermaid.initialize({ startOnLoad: true });
+</script>
+`
+
+// plantumlEncoding is the base64 encoding of the PlantUML server urls.
+var plantumlEncoding = base64.NewEncoding("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-_").WithPadding(base64.NoPadding)
+
+// mathRenderer returns a renderer of math (TeX) blocks as HTML rendered by
+// KaTeX from the dist folder.
+func mathRenderer(katex string) FenceRenderer {
+	assets := []string{fmt.Sprintf(katexAssets, template.HTMLEscapeString(strings.TrimSuffix(katex, "/")))}
+	return func(content string) (string, []string) {
+		return fmt.Sprintf(`<div class="math">%s</div>`, template.HTMLEscapeString(content)), assets
+	}
+}
+
+// mermaidRenderer returns a renderer of mermaid diagrams as HTML rendered by
+// the mermaid module.
+func mermaidRenderer(mermaid string) FenceRenderer {
+	assets := []string{fmt.Sprintf(mermaidAssets, template.JSEscapeString(mermaid))}
+	return func(content string) (string, []string) {
+		return fmt.Sprintf(`<pre class="mermaid">%s</pre>`, template.HTMLEscapeString(content)), assets
+	}
+}
+
+// plantumlRenderer returns a renderer of PlantUML diagrams as images rendered
+// by a PlantUML server.
+func plantumlRenderer(server string) FenceRenderer {
+	return func(content string) (string, []string) {
+		var compressed bytes.Buffer
+		writer, _ := flate.NewWriter(&compressed, flate.BestCompression)
+		writer.Write([]byte(content))
+		writer.Close()
+		// PlantUML encodes the trailing bytes as a complete group
+		for compressed.Len()%3 != 0 {
+			compressed.WriteByte(0)
+		}
+		src := fmt.Sprintf("%s/svg/%s", strings.TrimSuffix(server, "/"), plantumlEncoding.EncodeToString(compressed.Bytes()))
+		return fmt.Sprintf(`<p class="plantuml"><img src="%s" alt="PlantUML diagram" /></p>`, template.HTMLEscapeString(src)), nil
+	}
+}
+
+// pageAssets collects the assets required by the blocks of a page, once each.
+type pageAssets struct {
+	used map[string]bool
+	html []string
+}
+
+// add adds the assets which are not on the page yet.
+func (a *pageAssets) add(assets ...string) {
+	if a.used == nil {
+		a.used = map[string]bool{}
+	}
+	for _, asset := range assets {
+		if !a.used[asset] {
+			a.used[asset] = true
+			a.html = append(a.html, asset)
+		}
+	}
+}
+
+// HTML returns the HTML of the assets of the page.
+func (a *pageAssets) HTML() template.HTML {
+	return template.HTML(strings.Join(a.html, ""))
+}
+
+// fences renders the fenced blocks of the markdowns.
+type fences struct {
+	renderers   map[string]FenceRenderer
+	highlighter highlighter
+}
+
+// newFences creates the built-in renderers of the fenced blocks whose url
+// (assets or server) is configured, and the registered renderers. The blocks
+// are highlighted otherwise, so that no content is sent to (or script loaded
+// from) a third party unless configured.
+func newFences(config MarkdownConfig) fences {
+	f := fences{
+		renderers:   map[string]FenceRenderer{},
+		highlighter: newHighlighter(config.HighlightStyle)}
+	if config.KaTeX != "" {
+		f.renderers["math"] = mathRenderer(config.KaTeX)
+		f.renderers["latex"] = f.renderers["math"]
+		f.renderers["katex"] = f.renderers["math"]
+	}
+	if config.Mermaid != "" {
+		f.renderers["mermaid"] = mermaidRenderer(config.Mermaid)
+	}
+	if config.PlantUML != "" {
+		f.renderers["plantuml"] = plantumlRenderer(config.PlantUML)
+		f.renderers["puml"] = f.renderers["plantuml"]
+	}
+	for language, renderer := range fenceRenderers {
+		f.renderers[language] = renderer
+	}
+	return f
+}
+
+// fenceLanguage returns the language of a fenced block, i.e. the first word of
+// its info string (```go).
+func fenceLanguage(fence *markdown.Fence) string {
+	fields := strings.Fields(fence.Params)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(fields[0])
+}
+
+// render renders the fenced blocks of the parsed tokens with the renderer of
+// their language, or highlights them if the language is known. The assets
+// required by the rendered blocks are added to the assets of the page.
+func (f fences) render(tokens []markdown.Token, assets *pageAssets) {
+	for i, token := range tokens {
+		fence, ok := token.(*markdown.Fence)
+		if !ok {
+			continue
+		}
+		language := fenceLanguage(fence)
+		if renderer, ok := f.renderers[language]; ok {
+			rendered, required := renderer(fence.Content)
+			tokens[i] = &markdown.HTMLBlock{Content: rendered + "\n"}
+			assets.add(required...)
+			continue
+		}
+		lexer := lexers.Get(language)
+		if lexer == nil {
+			continue
+		}
+		highlighted, err := f.highlighter.highlight(fence.Content, lexer)
+		if err != nil {
+			continue
+		}
+		tokens[i] = &markdown.HTMLBlock{Content: highlighted + "\n"}
+	}
+}
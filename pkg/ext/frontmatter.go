@@ -0,0 +1,136 @@
+package ext
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// FrontMatter describes the metadata at the top of a markdown, as YAML
+// (between --- lines) or TOML (between +++ lines).
+type FrontMatter struct {
+	Title       string
+	Description string
+	// name of the layout used to render the markdown
+	Layout string
+	Date   time.Time
+	Tags   []string
+	// drafts are not served unless the preview is enabled
+	Draft bool
+	// all the fields of the front matter
+	Params map[string]interface{}
+}
+
+// dateLayouts are the accepted formats of the date of a front matter.
+var dateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// splitFrontMatter splits the front matter from the content of a markdown.
+// The delimiter (--- or +++) is empty if there is no front matter.
+func splitFrontMatter(content []byte) (delimiter string, frontMatter []byte, body []byte) {
+	content = bytes.TrimPrefix(content, []byte("\ufeff"))
+	for _, delimiter := range []string{"---", "+++"} {
+		if !bytes.HasPrefix(content, []byte(delimiter+"\n")) && !bytes.HasPrefix(content, []byte(delimiter+"\r\n")) {
+			continue
+		}
+		// find the closing delimiter on its own line
+		start := bytes.IndexByte(content, '\n') + 1
+		for i := start; i < len(content); {
+			end := bytes.IndexByte(content[i:], '\n')
+			line := content[i:]
+			if end >= 0 {
+				line = content[i : i+end]
+			}
+			if string(bytes.TrimRight(line, " \t\r")) == delimiter {
+				if end < 0 {
+					return delimiter, content[start:i], []byte{}
+				}
+				return delimiter, content[start:i], content[i+end+1:]
+			}
+			if end < 0 {
+				break
+			}
+			i += end + 1
+		}
+	}
+	return "", nil, content
+}
+
+// parseFrontMatter parses and strips the front matter of a markdown. The
+// content is returned as is if there is no valid front matter (e.g. a
+// thematic break --- at the top).
+func parseFrontMatter(content []byte) (FrontMatter, []byte) {
+	delimiter, raw, body := splitFrontMatter(content)
+	if delimiter == "" {
+		return FrontMatter{}, content
+	}
+
+	params := map[string]interface{}{}
+	var err error
+	if delimiter == "+++" {
+		_, err = toml.Decode(string(raw), &params)
+	} else {
+		var values map[interface{}]interface{}
+		if err = yaml.Unmarshal(raw, &values); err == nil {
+			for key, value := range values {
+				params[fmt.Sprint(key)] = value
+			}
+		}
+	}
+	if err != nil {
+		return FrontMatter{}, content
+	}
+
+	frontMatter := FrontMatter{Params: params}
+	frontMatter.Title, _ = params["title"].(string)
+	frontMatter.Description, _ = params["description"].(string)
+	frontMatter.Layout, _ = params["layout"].(string)
+	switch draft := params["draft"].(type) {
+	case bool:
+		frontMatter.Draft = draft
+	case string:
+		frontMatter.Draft = strings.EqualFold(draft, "true")
+	}
+	switch date := params["date"].(type) {
+	case time.Time:
+		frontMatter.Date = date
+	case string:
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, date); err == nil {
+				frontMatter.Date = t
+				break
+			}
+		}
+	}
+	switch tags := params["tags"].(type) {
+	case []interface{}:
+		for _, tag := range tags {
+			frontMatter.Tags = append(frontMatter.Tags, fmt.Sprint(tag))
+		}
+	case string:
+		frontMatter.Tags = splitList(tags)
+	}
+	return frontMatter, body
+}
+
+// isDraft checks whether the front matter of a document marks it as a draft.
+func isDraft(content []byte) bool {
+	frontMatter, _ := parseFrontMatter(content)
+	return frontMatter.Draft
+}
+
+// maxFrontMatterSize is the number of bytes read at the start of a document
+// to find its front matter without reading the whole document.
+const maxFrontMatterSize = 64 * 1024
+
+// readsDraft checks whether the front matter at the start of a document marks
+// it as a draft. Front matters longer than maxFrontMatterSize are ignored.
+func readsDraft(r io.Reader) bool {
+	content, err := ioutil.ReadAll(io.LimitReader(r, maxFrontMatterSize))
+	return err == nil && isDraft(content)
+}
@@ -0,0 +1,40 @@
+package ext
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// endlessReader returns an endless document body.
+type endlessReader struct{}
+
+func (endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'a'
+	}
+	return len(p), nil
+}
+
+func TestReadsDraft(t *testing.T) {
+	tests := []struct {
+		name  string
+		start string
+		draft bool
+	}{
+		{"yaml draft", "---\ndraft: true\n---\n", true},
+		{"toml draft", "+++\ndraft = true\n+++\n", true},
+		{"not a draft", "---\ntitle: Report\n---\n", false},
+		{"no front matter", "# Report\n", false},
+		{"front matter too long", "---\ntitle: " + strings.Repeat("a", maxFrontMatterSize) + "\ndraft: true\n---\n", false},
+	}
+	for _, test := range tests {
+		// only the start of the documents is read
+		if draft := readsDraft(io.MultiReader(strings.NewReader(test.start), endlessReader{})); draft != test.draft {
+			t.Errorf("%s: expected draft %t, got %t", test.name, test.draft, draft)
+		}
+	}
+	if !readsDraft(strings.NewReader("---\ndraft: true\n---")) {
+		t.Errorf("expected a short draft to be read entirely")
+	}
+}
@@ -0,0 +1,34 @@
+package ext
+
+import (
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/styles"
+)
+
+// highlighter highlights code as HTML with the inline styles of a chroma
+// style (https://xyproto.github.io/splash/docs/).
+type highlighter struct {
+	style *chroma.Style
+}
+
+// newHighlighter creates a highlighter for a style (default: github).
+func newHighlighter(style string) highlighter {
+	if style == "" {
+		style = "github"
+	}
+	return highlighter{style: styles.Get(style)}
+}
+
+// highlight returns the highlighted HTML of a code.
+func (h highlighter) highlight(code string, lexer chroma.Lexer, options ...html.Option) (string, error) {
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	err = html.New(append([]html.Option{html.TabWidth(4)}, options...)...).Format(&buf, h.style, iterator)
+	return buf.String(), err
+}
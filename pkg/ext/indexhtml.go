@@ -2,7 +2,10 @@
 
 import (
 	"fmt"
+	"net/http"
+	"net/url"
 	"path"
+	"strings"
 )
 
 // IndexHTML provides the decorator to insert a default index file to any
@@ -75,3 +78,60 @@ func (i IndexHTML) GetIndexHTML(handler Handler) Handler {
 		return Resource{}, error
 	}
 }
+
+// TrailingSlashExtension installs the extension to redirect (301) the folder
+// urls without a trailing slash (e.g. /docs/guide) to the folder (e.g.
+// /docs/guide/) if they are served with a default index file, so that the
+// relative links of the index file are resolved against the folder.
+func TrailingSlashExtension(helper *MinioHelper, enabled bool) Extension {
+	return func(c *Core) (string, error) {
+		if !enabled {
+			return "trailing slash redirect: disabled", nil
+		}
+		redirect := TrailingSlash{helper: helper, core: c}
+		c.ApplyRequest(redirect.RedirectToFolder)
+		return "trailing slash redirect: enabled", nil
+	}
+}
+
+// TrailingSlash provides the decorator to redirect folder urls to a trailing
+// slash.
+type TrailingSlash struct {
+	helper *MinioHelper
+	core   *Core
+}
+
+// isFolder checks whether the url of a served resource is a folder, i.e. the
+// object is inside the folder of the url.
+func (t TrailingSlash) isFolder(url string, info ResourceInfo) bool {
+	bucketName, prefix := t.helper.GetBucketNameAndPrefix(url)
+	if bucketName == "" || info.Bucket != bucketName || info.Key == "" {
+		return false
+	}
+	return strings.HasPrefix(info.Key, t.helper.Prefix+prefix+"/")
+}
+
+// RedirectToFolder decorates a RequestHandler to redirect the folder urls
+// without a trailing slash to the folder.
+func (t TrailingSlash) RedirectToFolder(handler RequestHandler) RequestHandler {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		folder := r.URL.Path
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) || folder == "" || strings.HasSuffix(folder, "/") {
+			handler(w, r)
+			return
+		}
+		res, err := t.core.StatObject(folder)
+		if err != nil || !t.isFolder(folder, res.Info) {
+			handler(w, r)
+			return
+		}
+		// relative to the request, as the url may be behind a base path
+		location := "./" + (&url.URL{Path: path.Base(folder) + "/"}).EscapedPath()
+		if r.URL.RawQuery != "" {
+			location += "?" + r.URL.RawQuery
+		}
+		w.Header().Set("Location", location)
+		w.WriteHeader(http.StatusMovedPermanently)
+	}
+}
@@ -0,0 +1,317 @@
+package ext
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwtLeeway is the allowed clock skew when validating the token timestamps.
+const jwtLeeway = 1 * time.Minute
+
+// jwksRefreshInterval is the min duration between refreshes of a remote JWKS.
+const jwksRefreshInterval = 1 * time.Minute
+
+// JWKS holds the public keys used to verify the signature of JWTs. Keys are
+// loaded from a local file or a remote url (refreshed for unknown key ids).
+type JWKS struct {
+	url         string
+	keys        map[string]crypto.PublicKey
+	refreshedAt time.Time
+	mutex       sync.RWMutex
+}
+
+// jsonWebKey is a RSA or EC public key in a JWKS.
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwtHeader is the JOSE header of a JWT.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Claims are the claims of a verified JWT.
+type Claims map[string]interface{}
+
+// NewJWKSFromFile loads a JWKS from a local file.
+func NewJWKSFromFile(path string) (*JWKS, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return nil, err
+	}
+	return &JWKS{keys: keys}, nil
+}
+
+// NewJWKSFromURL loads a JWKS from a remote url.
+func NewJWKSFromURL(url string) (*JWKS, error) {
+	jwks := &JWKS{url: url, keys: map[string]crypto.PublicKey{}}
+	return jwks, jwks.refresh()
+}
+
+// refresh retrieves the keys from the remote url.
+func (j *JWKS) refresh() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.url == "" || time.Since(j.refreshedAt) < jwksRefreshInterval {
+		return nil
+	}
+	j.refreshedAt = time.Now()
+
+	resp, err := http.Get(j.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS[%s]: %s", j.url, resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+	j.keys = keys
+	return nil
+}
+
+// getKey returns the public key with the key id.
+func (j *JWKS) getKey(kid string) (crypto.PublicKey, bool) {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, true
+		}
+	}
+	key, ok := j.keys[kid]
+	return key, ok
+}
+
+// parseJWKS parses the public keys (RSA and EC) inside a JWKS.
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, err
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+// publicKey converts the json web key into a public key.
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("JWKS[%s]: unsupported curve %s", jwk.Kid, jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("JWKS[%s]: unsupported key type %s", jwk.Kid, jwk.Kty)
+	}
+}
+
+// decodeBigInt decodes a base64url encoded big-endian integer.
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// Verify verifies the signature and timestamps of a JWT, and returns its
+// claims. Tokens without an expiry (exp) are rejected.
+func (j *JWKS) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := j.getKey(header.Kid)
+	if !ok {
+		// keys might have been rotated
+		j.refresh()
+		if key, ok = j.getKey(header.Kid); !ok {
+			return nil, fmt.Errorf("unknown key id: %s", header.Kid)
+		}
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	exp, ok := claims.time("exp")
+	if !ok {
+		return nil, errors.New("token without expiry")
+	}
+	if now.After(exp.Add(jwtLeeway)) {
+		return nil, errors.New("token expired")
+	}
+	if nbf, ok := claims.time("nbf"); ok && now.Before(nbf.Add(-jwtLeeway)) {
+		return nil, errors.New("token not valid yet")
+	}
+	return claims, nil
+}
+
+// decodeSegment decodes a base64url encoded json segment of a JWT.
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// verifySignature verifies a RSA or ECDSA signature of the signed content.
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported algorithm: %s", alg)
+	}
+	var hash crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm: %s", alg)
+	}
+	hasher := hash.New()
+	hasher.Write([]byte(signed))
+	digest := hasher.Sum(nil)
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("unsupported algorithm for RSA key: %s", alg)
+		}
+		return rsa.VerifyPKCS1v15(pub, hash, digest, signature)
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(alg, "ES") || len(signature) != 2*size {
+			return fmt.Errorf("invalid ECDSA signature for algorithm: %s", alg)
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		return errors.New("unsupported key")
+	}
+}
+
+// Value returns a string claim.
+func (c Claims) Value(name string) string {
+	value, _ := c[name].(string)
+	return value
+}
+
+// Values returns a claim which is either a string or a list of strings.
+func (c Claims) Values(name string) []string {
+	switch value := c[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var values []string
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// time returns a NumericDate claim.
+func (c Claims) time(name string) (time.Time, bool) {
+	value, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(value), 0), true
+}
@@ -0,0 +1,167 @@
+package ext
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testKeys are the keys signing the tokens of the tests.
+type testKeys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKeys{rsa: rsaKey, ec: ecKey}
+}
+
+// sign returns the signature of the content with the algorithm (RS256, ES256
+// or HS256 with the public RSA key as secret).
+func (k testKeys) sign(t *testing.T, alg string, content string) []byte {
+	digest := sha256.Sum256([]byte(content))
+	switch alg {
+	case "RS256":
+		signature, err := rsa.SignPKCS1v15(rand.Reader, k.rsa, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, k.ec, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature
+	case "HS256":
+		secret, err := x509.MarshalPKIXPublicKey(&k.rsa.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(content))
+		return mac.Sum(nil)
+	}
+	return nil
+}
+
+// token returns a JWT with the claims signed with the algorithm.
+func (k testKeys) token(t *testing.T, alg string, claims Claims) string {
+	header, _ := json.Marshal(jwtHeader{Alg: alg, Kid: "k1"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(k.sign(t, alg, signed))
+}
+
+func TestVerifySignature(t *testing.T) {
+	keys := newTestKeys(t)
+	content := "header.payload"
+
+	tests := []struct {
+		name      string
+		alg       string
+		key       crypto.PublicKey
+		signature []byte
+		valid     bool
+	}{
+		{"RS256 with RSA key", "RS256", &keys.rsa.PublicKey, keys.sign(t, "RS256", content), true},
+		{"ES256 with EC key", "ES256", &keys.ec.PublicKey, keys.sign(t, "ES256", content), true},
+		{"RS256 with EC key", "RS256", &keys.ec.PublicKey, keys.sign(t, "RS256", content), false},
+		{"ES256 with RSA key", "ES256", &keys.rsa.PublicKey, keys.sign(t, "ES256", content), false},
+		{"HS256 with RSA key", "HS256", &keys.rsa.PublicKey, keys.sign(t, "HS256", content), false},
+		{"none", "none", &keys.rsa.PublicKey, []byte{}, false},
+		{"none with a signature", "none", &keys.rsa.PublicKey, keys.sign(t, "RS256", content), false},
+		{"RS384 signed with RS256", "RS384", &keys.rsa.PublicKey, keys.sign(t, "RS256", content), false},
+		{"invalid RSA signature", "RS256", &keys.rsa.PublicKey, keys.sign(t, "RS256", "other"), false},
+		{"invalid EC signature", "ES256", &keys.ec.PublicKey, keys.sign(t, "ES256", "other"), false},
+		{"EC signature too short", "ES256", &keys.ec.PublicKey, keys.sign(t, "ES256", content)[:63], false},
+		{"unknown key type", "RS256", []byte("secret"), keys.sign(t, "RS256", content), false},
+	}
+	for _, test := range tests {
+		err := verifySignature(test.alg, test.key, content, test.signature)
+		if test.valid && err != nil {
+			t.Errorf("%s: expected a valid signature, got %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: expected an invalid signature", test.name)
+		}
+	}
+}
+
+func TestVerifyTimestamps(t *testing.T) {
+	keys := newTestKeys(t)
+	jwks := &JWKS{keys: map[string]crypto.PublicKey{"k1": &keys.rsa.PublicKey}}
+	now := time.Now()
+	exp := now.Add(time.Hour).Unix()
+
+	tests := []struct {
+		name   string
+		claims Claims
+		err    string
+	}{
+		{"no expiry", Claims{"sub": "a"}, "token without expiry"},
+		{"invalid expiry", Claims{"exp": "tomorrow"}, "token without expiry"},
+		{"not expired", Claims{"exp": exp}, ""},
+		{"expired within leeway", Claims{"exp": now.Add(-jwtLeeway / 2).Unix()}, ""},
+		{"expired", Claims{"exp": now.Add(-2 * jwtLeeway).Unix()}, "token expired"},
+		{"valid since", Claims{"exp": exp, "nbf": now.Add(-time.Hour).Unix()}, ""},
+		{"valid within leeway", Claims{"exp": exp, "nbf": now.Add(jwtLeeway / 2).Unix()}, ""},
+		{"not valid yet", Claims{"exp": exp, "nbf": now.Add(2 * jwtLeeway).Unix()}, "token not valid yet"},
+	}
+	for _, test := range tests {
+		_, err := jwks.Verify(keys.token(t, "RS256", test.claims))
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%s: expected a valid token, got %v", test.name, err)
+		case test.err != "" && (err == nil || err.Error() != test.err):
+			t.Errorf("%s: expected %q, got %v", test.name, test.err, err)
+		}
+	}
+}
+
+func TestVerifyRejectsForgedTokens(t *testing.T) {
+	keys := newTestKeys(t)
+	jwks := &JWKS{keys: map[string]crypto.PublicKey{"k1": &keys.rsa.PublicKey}}
+	claims := Claims{"sub": "a", "exp": time.Now().Add(time.Hour).Unix()}
+	valid := keys.token(t, "RS256", claims)
+	parts := strings.Split(valid, ".")
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"k1"}`))
+	other := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"admin"}`))
+
+	tests := map[string]string{
+		"HS256 with RSA key": keys.token(t, "HS256", claims),
+		"none":               none + "." + parts[1] + ".",
+		"modified claims":    parts[0] + "." + other + "." + parts[2],
+		"unknown key id":     base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","kid":"k2"}`)) + "." + parts[1] + "." + parts[2],
+		"malformed":          parts[0] + "." + parts[1],
+	}
+	if _, err := jwks.Verify(valid); err != nil {
+		t.Fatalf("expected a valid token, got %v", err)
+	}
+	for name, token := range tests {
+		if _, err := jwks.Verify(token); err == nil {
+			t.Errorf("%s: expected an invalid token", name)
+		}
+	}
+}
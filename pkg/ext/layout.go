@@ -0,0 +1,152 @@
+package ext
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bluele/gcache"
+	minio "github.com/minio/minio-go"
+)
+
+// layoutPartials are the partials of the layouts (e.g. partials/header.html)
+// which override the blocks of the same name in the page template.
+var layoutPartials = []string{"header", "nav", "footer"}
+
+// layouts provides the templates of the markdowns, i.e. the page template
+// with its blocks overridden by the partials and a layout, retrieved from a
+// directory or from the bucket.
+type layouts struct {
+	base   *template.Template
+	helper *MinioHelper
+	dir    string
+	prefix string
+	// compiled templates of each layout file (nil if missing)
+	templates gcache.Cache
+}
+
+// newLayouts creates the layouts of a page template, or nil if neither a
+// directory nor a prefix is configured.
+func newLayouts(base *template.Template, helper *MinioHelper, config MarkdownConfig) *layouts {
+	if config.Layouts == "" && config.LayoutsPrefix == "" {
+		return nil
+	}
+	l := &layouts{
+		base:   base,
+		helper: helper,
+		dir:    config.Layouts,
+		prefix: config.LayoutsPrefix}
+	refresh := time.Duration(config.LayoutsRefresh) * time.Second
+	if refresh <= 0 {
+		refresh = time.Minute
+	}
+	l.templates = gcache.New(1000).
+		ARC().
+		Expiration(refresh).
+		LoaderFunc(l.load).
+		Build()
+	return l
+}
+
+// read retrieves a file of the layouts, or nil if it does not exist.
+func (l *layouts) read(name string) ([]byte, error) {
+	if l.dir != "" {
+		content, err := ioutil.ReadFile(filepath.Join(l.dir, filepath.FromSlash(name)))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return content, err
+	}
+	res, err := l.helper.GetObject(path.Join("/", l.prefix, name))
+	if closer, ok := res.Data.(io.Closer); ok {
+		defer closer.Close()
+	}
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return ioutil.ReadAll(res.Data)
+}
+
+// load compiles the page template with the partials and a layout file (none
+// if the name is empty). A nil template is returned if the layout is missing.
+func (l *layouts) load(key interface{}) (interface{}, error) {
+	name := key.(string)
+	t, err := l.base.Clone()
+	if err != nil {
+		return nil, err
+	}
+	for _, partial := range layoutPartials {
+		content, err := l.read("partials/" + partial + ".html")
+		if err != nil {
+			return nil, err
+		}
+		if content == nil {
+			continue
+		}
+		if _, err := t.New(partial).Parse(string(content)); err != nil {
+			return nil, err
+		}
+	}
+	if name == "" {
+		return t, nil
+	}
+
+	content, err := l.read(name)
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		return (*template.Template)(nil), nil
+	}
+	if _, err := t.New(name).Parse(string(content)); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// get returns the compiled template of a layout file.
+func (l *layouts) get(name string) (*template.Template, error) {
+	t, err := l.templates.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*template.Template), nil
+}
+
+// template returns the template of a markdown: the layout named in its front
+// matter (e.g. layout: post for post.html), otherwise the nearest _layout.html
+// of the folders of its url (e.g. docs/_layout.html for /docs/a/b.md),
+// otherwise the page template.
+func (l *layouts) template(url string, layout string) (*template.Template, error) {
+	if layout != "" {
+		name := strings.TrimPrefix(path.Clean("/"+layout), "/")
+		if path.Ext(name) == "" {
+			name += ".html"
+		}
+		t, err := l.get(name)
+		if err == nil && t == nil {
+			err = fmt.Errorf("layout not found: %s", layout)
+		}
+		return t, err
+	}
+
+	for folder := path.Dir(url); ; folder = path.Dir(folder) {
+		t, err := l.get(strings.TrimPrefix(path.Join(folder, "_layout.html"), "/"))
+		if err != nil || t != nil {
+			return t, err
+		}
+		if folder == "/" || folder == "." {
+			break
+		}
+	}
+	return l.get("")
+}
@@ -0,0 +1,61 @@
+package ext
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"gitlab.com/golang-commonmark/markdown"
+)
+
+// linkRewriter resolves the relative links and images of a rendered document
+// against the url of its object, so that they also work when the document is
+// served as the index file of a folder url without a trailing slash (e.g.
+// /docs/guide for /docs/guide/README.md).
+type linkRewriter struct {
+	basePath string
+	// links to index files are rewritten to their folder (e.g. a/README.md
+	// to a/)
+	indexFiles []string
+}
+
+// newLinkRewriter creates a linkRewriter for the served urls behind the base
+// path.
+func newLinkRewriter(basePath string, indexFiles []string) linkRewriter {
+	return linkRewriter{basePath: normalizeBasePath(basePath), indexFiles: indexFiles}
+}
+
+// resolve returns the served url of a link relative to the url of an object.
+// Absolute and fragment only links are returned as is.
+func (l linkRewriter) resolve(link string, objectURL string) string {
+	ref, err := url.Parse(link)
+	if err != nil || ref.Scheme != "" || ref.Host != "" || ref.Path == "" || strings.HasPrefix(ref.Path, "/") {
+		return link
+	}
+	base := &url.URL{Path: l.basePath + objectURL}
+	resolved := base.ResolveReference(ref)
+	for _, indexFile := range l.indexFiles {
+		if indexFile != "" && path.Base(resolved.Path) == indexFile {
+			resolved.Path = strings.TrimSuffix(resolved.Path, indexFile)
+			resolved.RawPath = ""
+			break
+		}
+	}
+	return resolved.String()
+}
+
+// rewrite resolves the relative links and images of the parsed tokens of a
+// document against the url of its object.
+func (l linkRewriter) rewrite(tokens []markdown.Token, objectURL string) {
+	for _, token := range tokens {
+		switch token := token.(type) {
+		case *markdown.Inline:
+			l.rewrite(token.Children, objectURL)
+		case *markdown.LinkOpen:
+			token.Href = l.resolve(token.Href, objectURL)
+		case *markdown.Image:
+			token.Src = l.resolve(token.Src, objectURL)
+			l.rewrite(token.Tokens, objectURL)
+		}
+	}
+}
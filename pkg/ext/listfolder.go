@@ -2,40 +2,405 @@
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/bluele/gcache"
 	humanize "github.com/dustin/go-humanize"
 	glob "github.com/gobwas/glob"
 
 	core "github.com/e2fyi/minio-web/pkg/core"
+	minio "github.com/minio/minio-go"
 )
 
-const listingTemplate = `
-## {{.BucketName}}{{.URL}}
-
-| Name | Last Modified | Size |
-| --- | --- | --- |
-| <a href="..">. .</a> | | |
-{{range .ListingItems}} | <a href="{{.Path}}">{{.Name}}</a> | {{.LastModified}} | {{.Size}} |  
-{{end}}
+// listingTemplate is the default HTML template of a folder listing.
+const listingTemplate = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8" />
+  <meta name="viewport" content="width=device-width, initial-scale=1" />
+  <title>{{.URL}}</title>
+  <style>
+    body {
+      color: #24292e;
+      font-family: -apple-system, BlinkMacSystemFont, Segoe UI, Helvetica, Arial,
+        sans-serif;
+      margin: 0 auto;
+      max-width: 980px;
+      padding: 45px;
+    }
+    a {
+      color: #0366d6;
+      text-decoration: none;
+    }
+    a:hover {
+      text-decoration: underline;
+    }
+    table {
+      border-collapse: collapse;
+      width: 100%;
+    }
+    th,
+    td {
+      border-bottom: 1px solid #eaecef;
+      padding: 6px 13px;
+      text-align: left;
+    }
+    .size {
+      text-align: right;
+    }
+    ul.tree {
+      list-style: none;
+      padding-left: 1.5em;
+    }
+    small {
+      color: #6a737d;
+    }
+  </style>
+</head>
+<body>
+  <h2>{{range $i, $crumb := .Breadcrumbs}}{{if $i}} / {{end}}<a href="{{$crumb.URL}}">{{$crumb.Name}}</a>{{end}}</h2>
+  {{- if .SearchURL}}
+  <form action="{{.SearchURL}}">
+    <input type="hidden" name="prefix" value="{{.Path}}" />
+    <input type="search" name="q" value="{{.Query}}" placeholder="Search" />
+    {{- if .FullTextSearch}}
+    <select name="mode">
+      <option value="name">Names</option>
+      <option value="text"{{if eq .SearchMode "text"}} selected{{end}}>Full text</option>
+    </select>
+    {{- end}}
+    <button type="submit">Search</button>
+  </form>
+  {{- end}}
+  {{- if .Query}}
+  <p>{{len .Items}} result(s) for <strong>{{.Query}}</strong></p>
+  {{- end}}
+  <p>
+    <a href="{{.ListView}}">List</a> |
+    <a href="{{.TreeView}}">Tree</a> |
+    <a href="{{.FlatView}}">All objects</a>
+  </p>
+  {{- if .PageSorted}}
+  <p><small>Only the objects of this page are sorted, the pages are in the order of the names.</small></p>
+  {{- end}}
+  {{- if .Tree}}
+  {{- if .Parent}}
+  <div>&#x21a9; <a href="{{.Parent}}">..</a></div>
+  {{- end}}
+  {{template "tree" .Items}}
+  {{- else}}
+  <table>
+    <thead>
+      <tr>
+        <th><a href="{{.SortByName}}">Name</a></th>
+        <th><a href="{{.SortByDate}}">Last Modified</a></th>
+        <th class="size"><a href="{{.SortBySize}}">Size</a></th>
+      </tr>
+    </thead>
+    <tbody>
+      {{- if .Parent}}
+      <tr><td>&#x21a9; <a href="{{.Parent}}">..</a></td><td></td><td></td></tr>
+      {{- end}}
+      {{- range .Items}}
+      <tr>
+        <td>{{.Icon}} <a href="{{.URL}}">{{.Name}}</a>{{if .Snippet}}<br /><small>{{.Snippet}}</small>{{end}}</td>
+        <td>{{.HumanTime}}</td>
+        <td class="size">{{.HumanSize}}</td>
+      </tr>
+      {{- end}}
+    </tbody>
+  </table>
+  {{- end}}
+  {{- if .Truncated}}
+  <p>The listing is truncated, not all objects are shown.</p>
+  {{- end}}
+  <p>{{if .Prev}}<a href="{{.Prev}}">&laquo; Previous</a>{{end}} {{if .Next}}<a href="{{.Next}}">Next &raquo;</a>{{end}}</p>
+  {{- if .Readme}}
+  <article>{{.Readme}}</article>
+  {{- end}}
+</body>
+</html>
+{{- define "tree"}}
+  <ul class="tree">
+    {{- range .}}
+    {{- if .IsPrefix}}
+    <li>
+      <details>
+        <summary>{{.Icon}} <a href="{{.URL}}">{{.Name}}</a> <small>{{.Count}} object(s) {{.HumanSize}}</small></summary>
+        {{template "tree" .Children}}
+      </details>
+    </li>
+    {{- else}}
+    <li>{{.Icon}} <a href="{{.URL}}">{{.Name}}</a> <small>{{.HumanSize}}</small></li>
+    {{- end}}
+    {{- end}}
+  </ul>
+{{- end}}
 `
 
-type listingItem struct {
-	Name         string
-	Path         string
-	Size         string
-	LastModified string
+// listingIcons are the default icons of the objects by extension.
+var listingIcons = map[string]string{
+	".md":   "\U0001f4dd",
+	".txt":  "\U0001f4c4",
+	".html": "\U0001f310",
+	".htm":  "\U0001f310",
+	".pdf":  "\U0001f4d5",
+	".csv":  "\U0001f4ca",
+	".tsv":  "\U0001f4ca",
+	".json": "\U0001f4cb",
+	".jpg":  "\U0001f5bc",
+	".jpeg": "\U0001f5bc",
+	".png":  "\U0001f5bc",
+	".gif":  "\U0001f5bc",
+	".svg":  "\U0001f5bc",
+	".mp3":  "\U0001f3b5",
+	".mp4":  "\U0001f3ac",
+	".zip":  "\U0001f4e6",
+	".gz":   "\U0001f4e6",
+	".tar":  "\U0001f4e6",
+}
+
+const (
+	folderIcon = "\U0001f4c1"
+	objectIcon = "\U0001f4c4"
+)
+
+// maxListingPageSize is the max number of keys returned by ListObjectsV2.
+const maxListingPageSize = 1000
+
+// ListingConfig is used to config the folder listings.
+type ListingConfig struct {
+	// Number of objects and folders in a page (default and max: 1000). Can be
+	// changed with ?limit=n.
+	PageSize int `json:"pagesize"`
+	// Path to a html/template file used to render the listings (see
+	// ListingData for the fields available).
+	Template string `json:"template"`
+	// Comma separated list of url prefixes (e.g. /docs/) where the listing of
+	// a folder (url ending with /) is followed by the README of the folder,
+	// instead of serving the README as the default index file.
+	Readme string `json:"readme"`
+	// Name of the README file of a folder (default: README.md).
+	ReadmeFile string `json:"readmefile"`
+	// Comma separated list of globs of the objects and folders to list (e.g.
+	// *.md,*/ for markdowns and any folder). Default: folders and objects
+	// matching listfolderobjects.
+	Include string `json:"include"`
+	// Comma separated list of globs of the objects and folders to hide (e.g.
+	// _drafts/, *.tmp). Default: .* (hidden files).
+	Exclude string `json:"exclude"`
+	// Max depth of a recursive listing (default: 5). Can be reduced with
+	// ?depth=n.
+	MaxDepth int `json:"maxdepth"`
+	// Max number of objects walked by a recursive listing (default: 10000).
+	MaxObjects int `json:"maxobjects"`
+	// Search of objects by name, or by content (full-text search).
+	Search SearchConfig `json:"search"`
+	// Include and exclude globs for folders under specific url prefixes (the
+	// longest matching prefix applies instead of the defaults).
+	Rules []ListingRule `json:"rules"`
+}
+
+// ListingRule describes the objects and folders to list or hide under a url
+// prefix. Globs are matched against the name of the object or folder (e.g.
+// *.tmp, _drafts/), or against the url if they start with / (e.g.
+// /docs/build/**). Hidden objects and folders are still served if requested.
+type ListingRule struct {
+	Prefix  string `json:"prefix"`
+	Include string `json:"include"`
+	Exclude string `json:"exclude"`
+}
+
+// listingRule is a compiled ListingRule.
+type listingRule struct {
+	prefix  string
+	include []glob.Glob
+	exclude []glob.Glob
+}
+
+// matches checks whether an object or folder is listed by the rule. A folder
+// is matched with and without the trailing slash (e.g. .git and .git/).
+func (rule listingRule) matches(name string, url string) bool {
+	values := []string{name, url}
+	if strings.HasSuffix(name, "/") {
+		values = append(values, strings.TrimSuffix(name, "/"), strings.TrimSuffix(url, "/"))
+	}
+	included := len(rule.include) == 0
+	for _, value := range values {
+		if matchAny(rule.exclude, value) {
+			return false
+		}
+		included = included || matchAny(rule.include, value)
+	}
+	return included
+}
+
+// ListingData provides the view of a folder listing to the HTML template.
+type ListingData struct {
+	Bucket string
+	Prefix string
+	// url of the folder (including the base path)
+	URL string
+	// path of the folder (without the base path)
+	Path string
+	// links to each parent folder (starting with the root)
+	Breadcrumbs []Breadcrumb
+	// url of the parent folder (empty at the root)
+	Parent string
+	Items  []ListingItem
+	// urls of the next and previous pages (if any)
+	Next string
+	Prev string
+	// urls of the listing sorted by each field
+	SortByName string
+	SortBySize string
+	SortByDate string
+	// whether only the items of the page are sorted (i.e. the pages of a
+	// listing are in the order of the names)
+	PageSorted bool
+	// rendered README of the folder (if shown)
+	Readme template.HTML
+	// recursive listing (?recursive=1) as a tree (items with children) or a
+	// flat list of objects, truncated if there are too many objects
+	Recursive bool
+	Tree      bool
+	Truncated bool
+	// urls of the listing, and of the recursive listing as a tree or a flat
+	// list
+	ListView string
+	TreeView string
+	FlatView string
+	// url of the search endpoint (if enabled), and the query and the mode
+	// (name or text) of the search results
+	SearchURL      string
+	FullTextSearch bool
+	Query          string
+	SearchMode     string
 }
 
-type listing struct {
-	BucketName   string
-	Prefix       string
+// Breadcrumb is a link to a folder in the path of the listed folder.
+type Breadcrumb struct {
+	Name string
+	URL  string
+}
+
+// ListingItem describes an object or a folder (prefix) inside a folder.
+type ListingItem struct {
+	Name         string
+	Key          string
 	URL          string
-	ListingItems []listingItem
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	ContentType  string
+	IsPrefix     bool
+	// extension of the object (e.g. .md)
+	Ext  string
+	Icon string
+	// recursive listings only: number of objects inside a folder, and the
+	// objects and folders inside the folder (tree view)
+	Count    int
+	Children []ListingItem
+	// full-text search results only: text around the match
+	Snippet string
+}
+
+// HumanSize returns the size in a human readable format (e.g. 1.2 MB), or
+// an empty string for folders and empty objects.
+func (item ListingItem) HumanSize() string {
+	if item.Size == 0 {
+		return ""
+	}
+	return humanize.Bytes(uint64(item.Size))
+}
+
+// HumanTime returns the last modified time relative to now (e.g. 2 days
+// ago), or an empty string if unknown.
+func (item ListingItem) HumanTime() string {
+	if item.LastModified.IsZero() {
+		return ""
+	}
+	return humanize.Time(item.LastModified)
+}
+
+// listingOptions describes the page and the order of a listing, i.e.
+// ?token=...&limit=100&sort=size&order=desc
+type listingOptions struct {
+	Token    string
+	PageSize int
+	// name, size or date
+	Sort   string
+	Desc   bool
+	Format string
+	// recursive listing up to a depth, as a tree or a flat list, i.e.
+	// ?recursive=1&depth=2&view=flat
+	Recursive bool
+	Depth     int
+	Flat      bool
+	// checks whether the objects of a recursive listing can be accessed by
+	// the request (nil allows all objects)
+	Allowed func(url string) bool
+}
+
+// listingPage is a page of the objects and folders inside a folder.
+type listingPage struct {
+	BucketName string
+	Prefix     string
+	URL        string
+	Items      []ListingItem
+	// urls of the next and previous pages (if any)
+	Next string
+	Prev string
+	// whether only the items of the page are sorted
+	PageSorted bool
+	// whether a recursive listing or a search stopped at the max number of
+	// objects or results
+	Truncated bool
+	// query of the search results
+	Query string
+}
+
+// jsonListing is the machine-readable listing of a folder.
+type jsonListing struct {
+	Bucket string            `json:"bucket"`
+	Prefix string            `json:"prefix"`
+	Items  []jsonListingItem `json:"items"`
+	Next   string            `json:"next,omitempty"`
+	Prev   string            `json:"prev,omitempty"`
+	// only the items of the page are sorted
+	PageSorted bool `json:"pagesorted,omitempty"`
+	// recursive listings and search results only
+	Truncated bool   `json:"truncated,omitempty"`
+	Query     string `json:"query,omitempty"`
+}
+
+// jsonListingItem is the machine-readable description of a listing entry.
+type jsonListingItem struct {
+	Name         string `json:"name"`
+	Key          string `json:"key"`
+	Size         int64  `json:"size"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	ContentType  string `json:"contentType,omitempty"`
+	IsPrefix     bool   `json:"isPrefix"`
+	// recursive listings only
+	Count    int               `json:"count,omitempty"`
+	Children []jsonListingItem `json:"children,omitempty"`
+	// full-text search results only
+	Snippet string `json:"snippet,omitempty"`
 }
 
 // ListFolderExt describes the extension to list objects inside a pseudo-minio folder.
@@ -45,34 +410,94 @@ type ListFolderExt struct {
 	listFolder         bool
 	listFolderObjects  string
 	listFolderTemplate *template.Template
+	pageSize           int
+	// token of the previous page for each continuation token
+	prevTokens gcache.Cache
+	// include and exclude rules (longest prefix first)
+	rules []listingRule
+	// url prefixes where the README is shown after the listing
+	readmePrefixes []string
+	readmeFile     string
+	md             Markdown
+	// limits of the recursive listings
+	maxDepth   int
+	maxObjects int
+	// used to serve the listings requested with query parameters, and to
+	// retrieve the README of the folders
+	core *Core
+	// path prefix of the generated links (e.g. /files when served behind a
+	// proxy under /files)
+	basePath string
+	// search endpoint (nil if disabled)
+	search *listingSearch
 }
 
 // ListFolderExtension installs the extension to list folder objects.
-func ListFolderExtension(helper *MinioHelper, listFolder bool, listFolderObjects string) Extension {
+func ListFolderExtension(helper *MinioHelper, listFolder bool, listFolderObjects string, config ListingConfig, basePath string) Extension {
 	return func(c *Core) (string, error) {
 
-		ext, err := NewListFolderExt(helper, listFolder, listFolderObjects)
+		ext, err := NewListFolderExt(helper, listFolder, listFolderObjects, config, basePath)
 		if err != nil {
 			return "list folder: errored", err
 		}
-		c.ChainGetObject(ext.ListObjectsAsMarkdown)
-		return fmt.Sprintf("list folder objects: %s", listFolderObjects), nil
+		ext.core = c
+		c.ChainGetObject(ext.ListObjectsAsHTML)
+		c.ApplyRequest(ext.HandleListing)
+		if ext.search != nil {
+			c.ApplyRequest(ext.HandleSearch)
+		}
+		return fmt.Sprintf("list folder objects: %s (%d per page)", listFolderObjects, ext.pageSize), nil
 	}
 }
 
 // ListFolderExtension installs the extension to list folder objects.
 func (ext *ListFolderExt) decorateListFolderHandler(handler Handler) Handler {
-	return core.ChainHandlers(handler, ext.ListObjectsAsMarkdown)
+	return core.ChainHandlers(handler, ext.ListObjectsAsHTML)
 }
 
 // NewListFolderExt creates a new ListFolderExt object.
-func NewListFolderExt(helper *MinioHelper, listFolder bool, listFolderObjects string) (*ListFolderExt, error) {
+func NewListFolderExt(helper *MinioHelper, listFolder bool, listFolderObjects string, config ListingConfig, basePath string) (*ListFolderExt, error) {
 	if !listFolder {
 		return &ListFolderExt{
 			helper:     helper,
 			listFolder: listFolder}, nil
 	}
-	listFolderTemplate, err := template.New("listing").Parse(listingTemplate)
+	var listFolderTemplate *template.Template
+	var err error
+	if config.Template != "" {
+		listFolderTemplate, err = template.ParseFiles(config.Template)
+	} else {
+		listFolderTemplate, err = template.New("listing").Parse(listingTemplate)
+	}
+	pageSize := config.PageSize
+	if pageSize <= 0 || pageSize > maxListingPageSize {
+		pageSize = maxListingPageSize
+	}
+	readmeFile := config.ReadmeFile
+	if readmeFile == "" {
+		readmeFile = "README.md"
+	}
+	maxDepth := config.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = 5
+	}
+	maxObjects := config.MaxObjects
+	if maxObjects <= 0 {
+		maxObjects = 10000
+	}
+	rules, err := compileListingRules(config)
+	if err != nil {
+		return &ListFolderExt{}, err
+	}
+	search, err := newListingSearch(config.Search)
+	if err != nil {
+		return &ListFolderExt{}, err
+	}
+	// READMEs are rendered without template, layouts and table of contents
+	readme := Markdown{
+		md:     newMarkdownRenderer(),
+		fences: newFences(MarkdownConfig{}),
+		links:  newLinkRewriter(basePath, []string{readmeFile})}
 
 	if err == nil {
 		return &ListFolderExt{
@@ -80,102 +505,589 @@ func NewListFolderExt(helper *MinioHelper, listFolder bool, listFolderObjects st
 			helper:             helper,
 			listFolder:         listFolder,
 			listFolderObjects:  listFolderObjects,
-			listFolderTemplate: listFolderTemplate}, nil
+			listFolderTemplate: listFolderTemplate,
+			pageSize:           pageSize,
+			prevTokens:         gcache.New(10000).LRU().Expiration(1 * time.Hour).Build(),
+			rules:              rules,
+			readmePrefixes:     splitList(config.Readme),
+			readmeFile:         readmeFile,
+			md:                 readme,
+			maxDepth:           maxDepth,
+			maxObjects:         maxObjects,
+			basePath:           normalizeBasePath(basePath),
+			search:             search}, nil
 	}
 	return &ListFolderExt{}, err
 }
 
-// ListObjectsAsMarkdown retrieves (non-recursive) objects with a specified prefix
-// and rendered them as markdown Resource.
-func (ext *ListFolderExt) ListObjectsAsMarkdown(url string) (Resource, error) {
-	if !ext.listFolder {
-		return Resource{}, nil
+// compileListingRules compiles the default and the url prefix specific
+// include and exclude globs.
+func compileListingRules(config ListingConfig) ([]listingRule, error) {
+	rules := append([]ListingRule{{Prefix: "/", Include: config.Include, Exclude: config.Exclude}}, config.Rules...)
+
+	var compiled []listingRule
+	for _, rule := range rules {
+		include, err := compileGlobs(rule.Include)
+		if err != nil {
+			return nil, err
+		}
+		// hide hidden files by default
+		if rule.Exclude == "" {
+			rule.Exclude = ".*"
+		}
+		exclude, err := compileGlobs(rule.Exclude)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, listingRule{prefix: rule.Prefix, include: include, exclude: exclude})
 	}
-	// normalize url to directory
+	// longest prefix is matched first
+	sort.SliceStable(compiled, func(i, j int) bool {
+		return len(compiled[i].prefix) > len(compiled[j].prefix)
+	})
+	return compiled, nil
+}
+
+// isListed checks whether an object or folder inside a folder url is listed.
+func (ext *ListFolderExt) isListed(name string, url string, isPrefix bool) bool {
+	for _, rule := range ext.rules {
+		if !hasPathPrefix(url, rule.prefix) {
+			continue
+		}
+		if len(rule.include) == 0 && !isPrefix && !ext.pattern.Match(name) {
+			return false
+		}
+		return rule.matches(name, url+name)
+	}
+	return true
+}
+
+// normalizeFolderURL normalizes an url into a directory url.
+func normalizeFolderURL(url string) string {
 	switch n := len(url); {
 	case n == 0:
-		url = "/"
+		return "/"
 	case url[n-1] != '/':
-		url = url + "/"
+		return url + "/"
 	}
+	return url
+}
 
-	bucketName, prefix := ext.helper.GetBucketNameAndPrefix(url)
-	if bucketName == "" {
-		return Resource{Msg: fmt.Sprintf("GET[%s]: Bucket name not known", url)}, errors.New("Bucket name not known")
+// normalizeBasePath normalizes a base path into an absolute path without the
+// trailing slash (i.e. empty or /files).
+func normalizeBasePath(basePath string) string {
+	basePath = strings.Trim(basePath, "/")
+	if basePath == "" {
+		return ""
+	}
+	return "/" + basePath
+}
+
+// link returns the escaped url of a path (e.g. /docs/a b.md) behind the base
+// path.
+func (ext *ListFolderExt) link(p string) string {
+	return (&url.URL{Path: ext.basePath + p}).EscapedPath()
+}
+
+// defaultListingOptions returns the options of the first page of a listing.
+func (ext *ListFolderExt) defaultListingOptions() listingOptions {
+	return listingOptions{PageSize: ext.pageSize, Sort: "name", Depth: ext.maxDepth}
+}
+
+// parseListingOptions parses the page and order of a listing from the query
+// parameters.
+func (ext *ListFolderExt) parseListingOptions(query url.Values) (listingOptions, error) {
+	options := ext.defaultListingOptions()
+	options.Token = query.Get("token")
+	options.Format = query.Get("format")
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return options, fmt.Errorf("invalid limit: %s", limit)
+		}
+		if n > maxListingPageSize {
+			n = maxListingPageSize
+		}
+		options.PageSize = n
+	}
+	switch by := query.Get("sort"); by {
+	case "":
+	case "name", "size", "date":
+		options.Sort = by
+	default:
+		return options, fmt.Errorf("invalid sort: %s", by)
+	}
+	switch order := query.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		options.Desc = true
+	default:
+		return options, fmt.Errorf("invalid order: %s", order)
 	}
 
-	// Create a done channel to control 'ListObjectsV2' go routine.
-	doneCh := make(chan struct{})
+	switch recursive := query.Get("recursive"); recursive {
+	case "", "0", "false":
+	case "1", "true":
+		options.Recursive = true
+	default:
+		return options, fmt.Errorf("invalid recursive: %s", recursive)
+	}
+	if depth := query.Get("depth"); depth != "" {
+		n, err := strconv.Atoi(depth)
+		if err != nil || n <= 0 {
+			return options, fmt.Errorf("invalid depth: %s", depth)
+		}
+		if n < options.Depth {
+			options.Depth = n
+		}
+	}
+	switch view := query.Get("view"); view {
+	case "", "tree":
+	case "flat":
+		options.Flat = true
+	default:
+		return options, fmt.Errorf("invalid view: %s", view)
+	}
+	return options, nil
+}
 
-	// Indicate to our routine to exit cleanly upon return.
-	defer close(doneCh)
+// hasListingParams checks whether a specific page or order of a listing is
+// requested.
+func hasListingParams(r *http.Request) bool {
+	query := r.URL.Query()
+	for _, param := range []string{"token", "limit", "sort", "order", "recursive", "depth", "view"} {
+		if _, ok := query[param]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// pageURL returns the url of the listing of a folder with the options and
+// the continuation token.
+func (ext *ListFolderExt) pageURL(folder string, options listingOptions, token string) string {
+	query := url.Values{}
+	if token != "" {
+		query.Set("token", token)
+	}
+	if options.PageSize != ext.pageSize {
+		query.Set("limit", strconv.Itoa(options.PageSize))
+	}
+	if options.Sort != "name" {
+		query.Set("sort", options.Sort)
+	}
+	if options.Desc {
+		query.Set("order", "desc")
+	}
+	if options.Format != "" {
+		query.Set("format", options.Format)
+	}
+	if options.Recursive {
+		query.Set("recursive", "1")
+		if options.Depth != ext.maxDepth {
+			query.Set("depth", strconv.Itoa(options.Depth))
+		}
+		if options.Flat {
+			query.Set("view", "flat")
+		}
+	}
+	if len(query) == 0 {
+		return ext.link(folder)
+	}
+	return ext.link(folder) + "?" + query.Encode()
+}
+
+// sortURL returns the url of the first page of the listing sorted by a
+// field. The order is reversed if the listing is already sorted by the field.
+func (ext *ListFolderExt) sortURL(folder string, options listingOptions, by string) string {
+	options.Desc = options.Sort == by && !options.Desc
+	options.Sort = by
+	return ext.pageURL(folder, options, "")
+}
+
+// viewURL returns the url of the first page of the listing, or of the
+// recursive listing as a tree or a flat list.
+func (ext *ListFolderExt) viewURL(folder string, options listingOptions, recursive bool, flat bool) string {
+	options.Recursive = recursive
+	options.Flat = flat
+	return ext.pageURL(folder, options, "")
+}
+
+// sortItems sorts the items by name, size or date. Folders are listed
+// before objects.
+func sortItems(items []ListingItem, by string, desc bool) {
+	less := func(a, b ListingItem) bool {
+		switch by {
+		case "size":
+			return a.Size < b.Size
+		case "date":
+			return a.LastModified.Before(b.LastModified)
+		}
+		return a.Name < b.Name
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.IsPrefix != b.IsPrefix {
+			return a.IsPrefix
+		}
+		if desc {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+}
+
+// newListingItem creates the item of an object or a folder (name ending with
+// /) inside a folder url.
+func (ext *ListFolderExt) newListingItem(name string, url string, info minio.ObjectInfo) ListingItem {
+	isPrefix := strings.HasSuffix(name, "/")
+	contentType := info.ContentType
+	if contentType == "" && !isPrefix {
+		contentType = mime.TypeByExtension(path.Ext(name))
+	}
+	icon, extension := folderIcon, ""
+	if !isPrefix {
+		extension = strings.ToLower(path.Ext(name))
+		if icon = listingIcons[extension]; icon == "" {
+			icon = objectIcon
+		}
+	}
+	return ListingItem{
+		Name:         name,
+		Key:          info.Key,
+		URL:          ext.link(url + name),
+		Size:         info.Size,
+		ETag:         strings.Trim(info.ETag, `"`),
+		LastModified: info.LastModified,
+		ContentType:  contentType,
+		IsPrefix:     isPrefix,
+		Ext:          extension,
+		Icon:         icon}
+}
+
+// listObjects retrieves a page of (non-recursive) objects and folders with
+// the prefix of the url. Only the objects of the page are sorted, as the
+// backend lists the objects by key.
+func (ext *ListFolderExt) listObjects(url string, options listingOptions) (listingPage, error) {
+	bucketName, prefix := ext.helper.GetBucketNameAndPrefix(url)
+	page := listingPage{BucketName: bucketName, URL: url}
+	if bucketName == "" {
+		return page, errors.New("Bucket name not known")
+	}
 
 	// add user provided prefix if any
 	prefix = ext.helper.Prefix + prefix
+	page.Prefix = prefix
 
-	// list folders inside folder
+	// list a page of folders inside folder
 	isRecursive := false
-	objectCh := ext.helper.Client.ListObjectsV2(bucketName, prefix, isRecursive, doneCh)
-	var items []listingItem
-	for info := range objectCh {
+	result, err := ext.helper.ListObjectsPage(bucketName, prefix, options.Token, isRecursive, options.PageSize)
+	if err != nil {
+		return page, err
+	}
+
+	infos := result.Contents
+	for _, commonPrefix := range result.CommonPrefixes {
+		infos = append(infos, minio.ObjectInfo{Key: commonPrefix.Prefix})
+	}
+	for _, info := range infos {
 		// get actual filename
-		name := strings.Replace(info.Key, prefix, "", 1)
+		name := strings.TrimPrefix(info.Key, prefix)
 
 		// in case of errors
 		if len(name) <= 0 {
 			continue
 		}
 
-		// filter hidden files
-		if name[0] == '.' {
+		// filter hidden or excluded objects and folders
+		isPrefix := strings.HasSuffix(name, "/")
+		if !ext.isListed(name, url, isPrefix) {
 			continue
 		}
 
-		// filter objects don't match glob
-		if info.Size != 0 && !ext.pattern.Match(name) {
-			continue
-		}
+		page.Items = append(page.Items, ext.newListingItem(name, url, info))
+	}
+	sortItems(page.Items, options.Sort, options.Desc)
 
-		// dun render 0 bytes
-		var size string
-		if info.Size == 0 {
-			size = ""
-		} else {
-			size = humanize.Bytes(uint64(info.Size))
+	// continuation tokens only go forward, hence the token of the previous
+	// page is remembered when the next page is listed.
+	cacheKey := func(token string) string { return bucketName + "/" + prefix + "?" + token }
+	if result.IsTruncated && result.NextContinuationToken != "" {
+		ext.prevTokens.Set(cacheKey(result.NextContinuationToken), options.Token)
+		page.Next = ext.pageURL(url, options, result.NextContinuationToken)
+	}
+	if options.Token != "" {
+		prevToken, err := ext.prevTokens.Get(cacheKey(options.Token))
+		if err != nil {
+			// fallback to the first page if unknown
+			prevToken = ""
 		}
+		page.Prev = ext.pageURL(url, options, prevToken.(string))
+	}
+	// the backend lists the objects in the order of the names
+	page.PageSorted = (page.Next != "" || page.Prev != "") && (options.Sort != "name" || options.Desc)
+	return page, nil
+}
 
-		// dun render 0 timestamp
-		var lastModified string
-		if info.LastModified == (time.Time{}) {
-			lastModified = ""
-		} else {
-			lastModified = humanize.Time(info.LastModified)
+// breadcrumbs returns the links to each folder in the path of a folder url.
+// The root is named after the bucket, or the first folder is the bucket if
+// the bucket name is inferred from the url.
+func (ext *ListFolderExt) breadcrumbs(url string) []Breadcrumb {
+	root := ext.helper.BucketName
+	if root == "" {
+		root = "/"
+	}
+	crumbs := []Breadcrumb{{Name: root, URL: ext.link("/")}}
+	current := "/"
+	for _, segment := range strings.Split(strings.Trim(url, "/"), "/") {
+		if segment == "" {
+			continue
 		}
+		current += segment + "/"
+		crumbs = append(crumbs, Breadcrumb{Name: segment, URL: ext.link(current)})
+	}
+	return crumbs
+}
 
-		items = append(items,
-			listingItem{
-				Name:         name,
-				Path:         url + name,
-				Size:         size,
-				LastModified: lastModified})
+// parentURL returns the url of the parent of a folder url, or an empty
+// string at the root.
+func (ext *ListFolderExt) parentURL(url string) string {
+	if url == "/" {
+		return ""
 	}
+	return ext.link(normalizeFolderURL(path.Dir(strings.TrimSuffix(url, "/"))))
+}
 
-	var renderedMarkdown bytes.Buffer
-	err := ext.listFolderTemplate.Execute(&renderedMarkdown,
-		listing{
-			BucketName:   ext.helper.BucketName,
-			Prefix:       prefix,
-			URL:          url,
-			ListingItems: items})
+// showsReadme checks whether the README is shown after the listing of a
+// folder url.
+func (ext *ListFolderExt) showsReadme(url string) bool {
+	return strings.HasSuffix(url, "/") && hasAnyPrefix(url, ext.readmePrefixes)
+}
+
+// readme retrieves the README of a folder with the GetObject handler and
+// renders it as HTML. An empty string is returned if there is no README.
+func (ext *ListFolderExt) readme(url string) template.HTML {
+	if ext.core == nil || ext.core.GetObject == nil {
+		return ""
+	}
+	res, err := ext.core.GetObject(url + ext.readmeFile)
+	if closer, ok := res.Data.(io.Closer); ok {
+		defer closer.Close()
+	}
+	// the listing handler also succeeds for a missing README
+	renderer := documentRenderer(res)
+	if err != nil || res.Data == nil || renderer == nil {
+		return ""
+	}
+	content, err := ioutil.ReadAll(res.Data)
+	if err != nil {
+		return ""
+	}
+	// drafts are not shown (even if previewed)
+	frontMatter, body := parseFrontMatter(content)
+	if frontMatter.Draft {
+		return ""
+	}
+	body, err = renderer(body)
+	if err != nil {
+		return ""
+	}
+	rendered, _, assets := ext.md.render(body, url+ext.readmeFile)
+	return template.HTML(rendered) + assets
+}
+
+// renderListing renders a page of a listing as a HTML Resource.
+func (ext *ListFolderExt) renderListing(url string, options listingOptions) (Resource, error) {
+	// normalize url to directory
+	url = normalizeFolderURL(url)
+
+	page, err := ext.list(url, options)
+	if page.BucketName == "" {
+		return Resource{Msg: fmt.Sprintf("GET[%s]: Bucket name not known", url)}, err
+	}
+	if err != nil {
+		return Resource{Msg: fmt.Sprintf("ListObjectsV2[%s/%s]: %v", page.BucketName, page.Prefix, err)}, err
+	}
+
+	data := ext.listingData(url, options, page)
+	if ext.showsReadme(url) {
+		data.Readme = ext.readme(url)
+	}
+	return ext.render(data, fmt.Sprintf("ListObjectsV2[%s/%s] ok", page.BucketName, page.Prefix))
+}
+
+// listingData returns the view of a page of the listing of a folder url.
+func (ext *ListFolderExt) listingData(url string, options listingOptions, page listingPage) ListingData {
+	data := ListingData{
+		Bucket:      page.BucketName,
+		Prefix:      page.Prefix,
+		URL:         ext.basePath + url,
+		Path:        url,
+		Breadcrumbs: ext.breadcrumbs(url),
+		Parent:      ext.parentURL(url),
+		Items:       page.Items,
+		Next:        page.Next,
+		Prev:        page.Prev,
+		SortByName:  ext.sortURL(url, options, "name"),
+		SortBySize:  ext.sortURL(url, options, "size"),
+		SortByDate:  ext.sortURL(url, options, "date"),
+		PageSorted:  page.PageSorted,
+		Recursive:   options.Recursive,
+		Tree:        options.Recursive && !options.Flat,
+		Truncated:   page.Truncated,
+		ListView:    ext.viewURL(url, options, false, false),
+		TreeView:    ext.viewURL(url, options, true, false),
+		FlatView:    ext.viewURL(url, options, true, true)}
+	if ext.search != nil {
+		data.SearchURL = ext.link(ext.search.path)
+		data.FullTextSearch = ext.search.fullText
+	}
+	return data
+}
+
+// render renders the view of a listing as a HTML Resource.
+func (ext *ListFolderExt) render(data ListingData, msg string) (Resource, error) {
+	var rendered bytes.Buffer
+	err := ext.listFolderTemplate.Execute(&rendered, data)
 	if err != nil {
 		return Resource{}, err
 	}
 
 	return Resource{
-		Msg:  fmt.Sprintf("ListObjectsV2[%s/%s] ok", bucketName, prefix),
-		Data: bytes.NewReader(renderedMarkdown.Bytes()),
+		Msg:  msg,
+		Data: bytes.NewReader(rendered.Bytes()),
 		Info: ResourceInfo{
-			Size:         int64(len(renderedMarkdown.Bytes())),
-			ContentType:  "text/markdown",
+			Size:         int64(len(rendered.Bytes())),
+			ContentType:  "text/html; charset=utf-8",
 			LastModified: time.Now()}}, nil
 }
+
+// ListObjectsAsHTML retrieves the first page of (non-recursive) objects
+// with a specified prefix and rendered them as HTML Resource.
+func (ext *ListFolderExt) ListObjectsAsHTML(url string) (Resource, error) {
+	if !ext.listFolder {
+		return Resource{}, nil
+	}
+	return ext.renderListing(url, ext.defaultListingOptions())
+}
+
+// allowedURLs returns a function checking whether the request can access
+// other urls (e.g. the objects of a listing) as if they were requested. The
+// credentials of the request are only verified once, while the authorization
+// is checked for each url.
+func (ext *ListFolderExt) allowedURLs(r *http.Request) func(url string) bool {
+	r = withAuthCache(r)
+	return func(url string) bool {
+		return ext.core.AllowedURL(r, url)
+	}
+}
+
+// wantsJSON checks whether a machine-readable listing is requested (i.e.
+// ?format=json or Accept: application/json).
+func wantsJSON(r *http.Request) bool {
+	return r.URL.Query().Get("format") == "json" ||
+		strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// toJSONListingItems converts the items (and their children) into their
+// machine-readable description.
+func toJSONListingItems(items []ListingItem) []jsonListingItem {
+	var jsonItems []jsonListingItem
+	for _, item := range items {
+		jsonItem := jsonListingItem{
+			Name:        item.Name,
+			Key:         item.Key,
+			Size:        item.Size,
+			ETag:        item.ETag,
+			ContentType: item.ContentType,
+			IsPrefix:    item.IsPrefix,
+			Count:       item.Count,
+			Children:    toJSONListingItems(item.Children),
+			Snippet:     item.Snippet}
+		if !item.LastModified.IsZero() {
+			jsonItem.LastModified = item.LastModified.UTC().Format(time.RFC3339)
+		}
+		jsonItems = append(jsonItems, jsonItem)
+	}
+	return jsonItems
+}
+
+// serveJSON writes a page of a listing as json.
+func serveJSON(w http.ResponseWriter, r *http.Request, page listingPage) {
+	result := jsonListing{
+		Bucket:     page.BucketName,
+		Prefix:     page.Prefix,
+		Items:      []jsonListingItem{},
+		Next:       page.Next,
+		Prev:       page.Prev,
+		PageSorted: page.PageSorted,
+		Truncated:  page.Truncated,
+		Query:      page.Query}
+	result.Items = append(result.Items, toJSONListingItems(page.Items)...)
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	if r.Method == http.MethodGet {
+		w.Write(data)
+	}
+}
+
+// HandleListing decorates a RequestHandler to return the listing of a folder
+// as json, or a specific page or order of the listing, if requested, or the
+// listing followed by the README of the folder if configured. Requests for
+// an existing object are served as usual.
+func (ext *ListFolderExt) HandleListing(handler RequestHandler) RequestHandler {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !ext.listFolder || !(wantsJSON(r) || hasListingParams(r) || ext.showsReadme(r.URL.Path)) ||
+			(r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			handler(w, r)
+			return
+		}
+		url := r.URL.Path
+		if !strings.HasSuffix(url, "/") {
+			if _, err := ext.helper.StatObject(url); err == nil {
+				handler(w, r)
+				return
+			}
+		}
+
+		options, err := ext.parseListingOptions(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// the objects of the sub folders are checked as if they were
+		// requested (e.g. authorization of a restricted prefix)
+		options.Allowed = ext.allowedURLs(r)
+		if wantsJSON(r) {
+			page, err := ext.list(normalizeFolderURL(url), options)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			serveJSON(w, r, page)
+			return
+		}
+
+		res, err := ext.renderListing(url, options)
+		if res.Msg != "" {
+			ext.core.Sugar.Info(res.Msg)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodHead {
+			ext.core.SetHeaders(w, res.Info)
+			return
+		}
+		ext.core.ServeResource(w, r, res)
+	}
+}
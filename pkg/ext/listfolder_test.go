@@ -0,0 +1,89 @@
+package ext
+
+import (
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestListingRulePrefix(t *testing.T) {
+	ext, err := NewListFolderExt(&MinioHelper{}, true, "*", ListingConfig{
+		Exclude: ".*",
+		Rules:   []ListingRule{{Prefix: "/docs", Exclude: "*.md"}}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		folder string
+		name   string
+		listed bool
+	}{
+		{"/docs/", "a.md", false},
+		{"/docs/a/", "b.md", false},
+		{"/docs/", "a.txt", true},
+		{"/docs-private/", "a.md", true},
+		{"/docs-private/", ".hidden", false},
+		{"/", ".hidden", false},
+		{"/", "a.md", true},
+	}
+	for _, test := range tests {
+		if ext.isListed(test.name, test.folder, false) != test.listed {
+			t.Errorf("%s%s: expected listed %t", test.folder, test.name, test.listed)
+		}
+	}
+}
+
+func TestListingSortedWithinPage(t *testing.T) {
+	server, helper := newFakeBucket(t, map[string]string{"a.txt": "333", "b.txt": "1", "c.txt": "22", "d.txt": "4444"})
+	defer server.Close()
+	ext, err := NewListFolderExt(helper, true, "*", ListingConfig{PageSize: 2}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := func(page listingPage) string {
+		var names []string
+		for _, item := range page.Items {
+			names = append(names, item.Name)
+		}
+		return strings.Join(names, ",")
+	}
+	tests := []struct {
+		query      string
+		names      string
+		pageSorted bool
+	}{
+		{"", "a.txt,b.txt", false},
+		{"sort=size", "b.txt,a.txt", true},
+		{"sort=date&order=desc", "a.txt,b.txt", true},
+		{"order=desc", "b.txt,a.txt", true},
+		{"limit=4&sort=size", "b.txt,c.txt,a.txt,d.txt", false},
+	}
+	for _, test := range tests {
+		query, _ := url.ParseQuery(test.query)
+		options, err := ext.parseListingOptions(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, err := ext.list("/", options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names(page) != test.names || page.PageSorted != test.pageSorted {
+			t.Errorf("%s: expected %s (page sorted %t), got %s (%t)", test.query, test.names, test.pageSorted, names(page), page.PageSorted)
+		}
+	}
+
+	// the listing is labelled as sorted within the page
+	options, _ := ext.parseListingOptions(url.Values{"sort": {"size"}})
+	res, err := ext.renderListing("/", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := ioutil.ReadAll(res.Data)
+	if !strings.Contains(string(content), "Only the objects of this page are sorted") {
+		t.Errorf("expected the listing to be labelled as sorted within the page")
+	}
+}
@@ -0,0 +1,155 @@
+package ext
+
+import (
+	"errors"
+	"strings"
+
+	minio "github.com/minio/minio-go"
+)
+
+// treeNode is a folder of a recursive listing.
+type treeNode struct {
+	item     ListingItem
+	children map[string]*treeNode
+	objects  []ListingItem
+}
+
+// newTreeNode creates a new treeNode for a folder item.
+func newTreeNode(item ListingItem) *treeNode {
+	return &treeNode{item: item, children: map[string]*treeNode{}}
+}
+
+// toItems returns the sorted objects and folders (with their children) inside
+// the folder.
+func (node *treeNode) toItems(by string, desc bool) []ListingItem {
+	items := append([]ListingItem{}, node.objects...)
+	for _, child := range node.children {
+		item := child.item
+		item.Children = child.toItems(by, desc)
+		items = append(items, item)
+	}
+	sortItems(items, by, desc)
+	return items
+}
+
+// list retrieves a page of the listing, or the recursive listing if
+// requested.
+func (ext *ListFolderExt) list(url string, options listingOptions) (listingPage, error) {
+	if options.Recursive {
+		return ext.walkObjects(url, options)
+	}
+	return ext.listObjects(url, options)
+}
+
+// walkObjects retrieves the objects with the prefix of the url recursively,
+// as a tree of folders up to the depth or as a flat list. The size and the
+// number of objects of the folders include the objects deeper than the depth.
+// Objects which are not allowed are skipped. At most maxObjects objects are
+// walked.
+func (ext *ListFolderExt) walkObjects(url string, options listingOptions) (listingPage, error) {
+	bucketName, prefix := ext.helper.GetBucketNameAndPrefix(url)
+	page := listingPage{BucketName: bucketName, URL: url}
+	if bucketName == "" {
+		return page, errors.New("Bucket name not known")
+	}
+
+	// add user provided prefix if any
+	prefix = ext.helper.Prefix + prefix
+	page.Prefix = prefix
+
+	root := newTreeNode(ListingItem{IsPrefix: true})
+	walked := 0
+	token := ""
+	for {
+		isRecursive := true
+		result, err := ext.helper.ListObjectsPage(bucketName, prefix, token, isRecursive, maxListingPageSize)
+		if err != nil {
+			return page, err
+		}
+		for _, info := range result.Contents {
+			if walked >= ext.maxObjects {
+				page.Truncated = true
+				break
+			}
+			walked++
+
+			name := strings.TrimPrefix(info.Key, prefix)
+			// skip folder markers
+			if name == "" || strings.HasSuffix(name, "/") {
+				continue
+			}
+			if options.Allowed != nil && !options.Allowed(url+name) {
+				continue
+			}
+			item, ok := ext.addToTree(root, url, prefix, name, info, options.Depth)
+			if ok && options.Flat {
+				// flat list of the paths relative to the folder
+				item.Name = name
+				page.Items = append(page.Items, item)
+			}
+		}
+		if page.Truncated || !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	if options.Flat {
+		sortItems(page.Items, options.Sort, options.Desc)
+	} else {
+		page.Items = root.toItems(options.Sort, options.Desc)
+	}
+	return page, nil
+}
+
+// isListedObject checks whether an object (name relative to the folder url,
+// e.g. a/b/c.md) and the folders containing it are listed.
+func (ext *ListFolderExt) isListedObject(url string, name string) bool {
+	segments := strings.Split(name, "/")
+	folder := url
+	for _, segment := range segments[:len(segments)-1] {
+		if !ext.isListed(segment+"/", folder, true) {
+			return false
+		}
+		folder += segment + "/"
+	}
+	return ext.isListed(segments[len(segments)-1], folder, false)
+}
+
+// addToTree adds an object (name relative to the folder url) to the folders
+// of the tree up to the depth. The item of the object is returned unless it
+// is hidden or deeper than the depth.
+func (ext *ListFolderExt) addToTree(root *treeNode, url string, prefix string, name string, info minio.ObjectInfo, depth int) (ListingItem, bool) {
+	if !ext.isListedObject(url, name) {
+		return ListingItem{}, false
+	}
+	segments := strings.Split(name, "/")
+	folders, objectName := segments[:len(segments)-1], segments[len(segments)-1]
+
+	// aggregate the object into the folders up to the depth
+	node := root
+	folder := url
+	for i := 0; i < len(folders) && i < depth; i++ {
+		folderName := folders[i] + "/"
+		child, ok := node.children[folderName]
+		if !ok {
+			key := prefix + strings.Join(folders[:i+1], "/") + "/"
+			child = newTreeNode(ext.newListingItem(folderName, folder, minio.ObjectInfo{Key: key}))
+			node.children[folderName] = child
+		}
+		child.item.Size += info.Size
+		child.item.Count++
+		if info.LastModified.After(child.item.LastModified) {
+			child.item.LastModified = info.LastModified
+		}
+		node = child
+		folder += folderName
+	}
+	if len(folders) >= depth {
+		return ListingItem{}, false
+	}
+
+	item := ext.newListingItem(objectName, folder, info)
+	node.objects = append(node.objects, item)
+	return item, true
+}
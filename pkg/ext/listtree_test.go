@@ -0,0 +1,79 @@
+package ext
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/e2fyi/minio-web/pkg/core"
+)
+
+func TestRecursiveListingChecksAccess(t *testing.T) {
+	server, helper := newFakeBucket(t, map[string]string{"docs/report.md": "", "internal/secret.md": ""})
+	defer server.Close()
+
+	c := core.NewCore()
+	c.ChainStatObject(helper.StatObject)
+	c.ChainGetObject(helper.GetObject)
+	c.ApplyExtension(ListFolderExtension(helper, true, "*", ListingConfig{}, ""))
+	c.ApplyExtension(AuthorizationExtension([]AuthPolicy{{Paths: "/internal/**", Groups: "admin"}}))
+	c.Init()
+	handler := c.Handler()
+
+	for _, url := range []string{"/?recursive=1&format=json", "/?recursive=1&view=flat&format=json"} {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, url, nil))
+		body := w.Body.String()
+		if w.Code != http.StatusOK || !strings.Contains(body, "report.md") {
+			t.Errorf("%s: expected the allowed objects, got %d %s", url, w.Code, body)
+		}
+		if strings.Contains(body, "secret.md") || strings.Contains(body, "internal") {
+			t.Errorf("%s: unexpected denied objects in %s", url, body)
+		}
+	}
+}
+
+func TestRecursiveListingAuthenticatesOnce(t *testing.T) {
+	server, helper := newFakeBucket(t, map[string]string{"docs/a.md": "", "private/a.md": "", "private/b.md": ""})
+	defer server.Close()
+	htpasswd := filepath.Join(t.TempDir(), "htpasswd")
+	// user:password
+	if err := ioutil.WriteFile(htpasswd, []byte("user:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := core.NewCore()
+	c.ChainStatObject(helper.StatObject)
+	c.ChainGetObject(helper.GetObject)
+	c.ApplyExtension(ListFolderExtension(helper, true, "*", ListingConfig{}, ""))
+	c.ApplyExtension(BasicAuthExtension(BasicAuthConfig{Realms: []BasicAuthRealm{{Prefix: "/private", HtpasswdFile: htpasswd}}}))
+	c.Init()
+	handler := c.Handler()
+
+	tests := []struct {
+		password string
+		private  bool
+	}{
+		{"password", true},
+		{"wrong", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/?recursive=1&view=flat&format=json", nil)
+		if test.password != "" {
+			r.SetBasicAuth("user", test.password)
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		body := w.Body.String()
+		if w.Code != http.StatusOK || !strings.Contains(body, "docs/a.md") {
+			t.Errorf("%q: expected the public objects, got %d %s", test.password, w.Code, body)
+		}
+		if strings.Contains(body, "private/b.md") != test.private {
+			t.Errorf("%q: expected private objects listed %t, got %s", test.password, test.private, body)
+		}
+	}
+}
@@ -3,67 +3,213 @@
 import (
 	"bytes"
 	"html/template"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"gitlab.com/golang-commonmark/markdown"
 )
 
-// Markdown provides the decorator to serve markdowns as HMTL.
+// MarkdownConfig is used to config the markdown rendering extension.
+type MarkdownConfig struct {
+	// Serve the markdowns with draft: true in their front matter instead of
+	// returning 404.
+	Preview bool `json:"preview"`
+	// Directory of the layouts, i.e. <name>.html selected by the front matter
+	// (layout: name), <folder>/_layout.html for the markdowns inside a folder,
+	// and partials/{header,nav,footer}.html. The layouts override the blocks
+	// of the markdown template, e.g. {{define "content"}}...{{end}}.
+	Layouts string `json:"layouts"`
+	// Url prefix of the layouts stored in the bucket instead of a directory
+	// (e.g. /_layouts/).
+	LayoutsPrefix string `json:"layoutsprefix"`
+	// Number of seconds the layouts are cached (default: 60).
+	LayoutsRefresh int `json:"layoutsrefresh"`
+	// Max level of the headings in the table of contents (default: 3).
+	TOCDepth int `json:"tocdepth"`
+	// Name of the chroma style of the fenced code blocks (default: github).
+	HighlightStyle string `json:"highlightstyle"`
+	// Url of the KaTeX dist folder used to render the math blocks (```math).
+	// The math blocks are highlighted if not set.
+	KaTeX string `json:"katex"`
+	// Url of the mermaid module used to render the mermaid blocks, which are
+	// highlighted if not set.
+	Mermaid string `json:"mermaid"`
+	// Url of the PlantUML server used to render the plantuml blocks, which are
+	// highlighted if not set. The source of the diagrams is sent to the server.
+	PlantUML string `json:"plantuml"`
+	// Render the jupyter notebooks (.ipynb) as HTML with the template.
+	Notebooks bool `json:"notebooks"`
+}
+
+// Markdown provides the decorator to serve markdowns (and the documents
+// converted to markdown, e.g. AsciiDoc) as HMTL.
 type Markdown struct {
 	template *template.Template
 	md       *markdown.Markdown
+	preview  bool
+	tocDepth int
+	fences   fences
+	links    linkRewriter
+	helper   *MinioHelper
+	core     *Core
+	// layouts of the markdowns, nil if not configured
+	layouts *layouts
 }
 
-// TemplateData provides the view to the HTML template.
+// TemplateData provides the view to the HTML template. The fields of the
+// front matter (e.g. .Title) are also available.
 type TemplateData struct {
 	Content template.HTML
+	// table of contents, i.e. {{ .TOC.HTML }}
+	TOC TOC
+	// assets (e.g. scripts) required by the rendered blocks (e.g. math)
+	Assets template.HTML
+	FrontMatter
 }
 
 // RenderMarkdownExtension installs the markdown extension if a template is
-// provided.
-func RenderMarkdownExtension(templateFile string) Extension {
+// provided. The relative links are resolved against the markdowns behind the
+// base path, and the links to index files are rewritten to their folder.
+func RenderMarkdownExtension(helper *MinioHelper, templateFile string, config MarkdownConfig, basePath string, indexFiles []string) Extension {
 	return func(c *Core) (string, error) {
-		decorator, err := getMarkdownDecorator(templateFile)
+		ext, err := NewMarkdown(helper, templateFile, config, basePath, indexFiles)
 		if err != nil {
 			return "markdown rendering: errored", err
 		}
-		c.ApplyServe(decorator)
+		ext.core = c
+		c.ApplyServe(ext.RenderDocument)
+		c.ApplyRequest(ext.HideDrafts)
+		if config.Notebooks {
+			c.ApplyServe(ext.RenderNotebook)
+			return "markdown rendering: enabled (with notebooks)", nil
+		}
 		return "markdown rendering: enabled", nil
 	}
 }
 
-// getMarkdownDecorator returns a ServeHandlerDecorator.
-func getMarkdownDecorator(templateFile string) (ServeHandlerDecorator, error) {
+// NewMarkdown creates a new Markdown object.
+func NewMarkdown(helper *MinioHelper, templateFile string, config MarkdownConfig, basePath string, indexFiles []string) (*Markdown, error) {
 	template, err := template.ParseFiles(templateFile)
 	if err != nil {
 		return nil, err
 	}
 
-	md := markdown.New(
+	ext := &Markdown{
+		template: template,
+		md:       newMarkdownRenderer(),
+		preview:  config.Preview,
+		tocDepth: config.TOCDepth,
+		fences:   newFences(config),
+		links:    newLinkRewriter(basePath, indexFiles),
+		helper:   helper,
+		layouts:  newLayouts(template, helper, config)}
+	if ext.tocDepth <= 0 {
+		ext.tocDepth = 3
+	}
+	return ext, nil
+}
+
+// newMarkdownRenderer creates a renderer from markdown to HTML.
+func newMarkdownRenderer() *markdown.Markdown {
+	return markdown.New(
 		markdown.HTML(true),
 		markdown.Tables(true),
 		markdown.Linkify(true),
 		markdown.Typographer(true),
 		markdown.XHTMLOutput(true))
+}
+
+// render renders a markdown as HTML with rendered fenced blocks, anchors for
+// its headings and links resolved against its url, and returns its table of
+// contents and the assets required by the fenced blocks.
+func (m Markdown) render(content []byte, url string) (string, TOC, template.HTML) {
+	anchors := newHeadingAnchors(m.tocDepth)
+	assets := &pageAssets{}
+	rendered := m.renderPart(content, url, anchors, assets)
+	return rendered, anchors.toc, assets.HTML()
+}
+
+// renderPart renders a markdown as HTML, with the anchors and assets shared by
+// the markdowns of a page (e.g. cells of a notebook).
+func (m Markdown) renderPart(content []byte, url string, anchors *headingAnchors, assets *pageAssets) string {
+	tokens := m.md.Parse(content)
+	if url != "" {
+		m.links.rewrite(tokens, url)
+	}
+	m.fences.render(tokens, assets)
+	anchors.add(tokens)
+	return m.md.RenderTokensToString(tokens)
+}
+
+// resourceURL returns the url of a resource, or an empty string if it is not
+// an object.
+func (m Markdown) resourceURL(info ResourceInfo) string {
+	if info.Key == "" || m.helper == nil {
+		return ""
+	}
+	return m.helper.GetURL(info.Bucket, info.Key)
+}
+
+// serve renders the page of a document at the url with the template (or its
+// layout), and writes it as HTML.
+func (m Markdown) serve(w http.ResponseWriter, url string, data TemplateData) error {
+	page := m.template
+	if m.layouts != nil {
+		var err error
+		page, err = m.layouts.template(url, data.Layout)
+		if err != nil {
+			return err
+		}
+	}
 
-	ext := Markdown{template: template, md: md}
-	return ext.RenderMarkdown, nil
+	var buf bytes.Buffer
+	err := page.Execute(&buf, data)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.FormatInt(int64(len(buf.Bytes())), 10))
+	_, err = w.Write(buf.Bytes())
+	return err
 }
 
-// isMarkdown checks whether a resource content type is a markdown.
-func isMarkdown(resource Resource) bool {
-	return strings.Contains(strings.ToLower(resource.Info.ContentType), "markdown") || strings.HasSuffix(strings.ToLower(resource.Info.Key), ".md")
+// HideDrafts decorates a RequestHandler to return 404 for the HEAD requests
+// of the drafts, as for their GET requests (HEAD requests are not served).
+// Only the start of the documents is read to find their front matter.
+func (m Markdown) HideDrafts(handler RequestHandler) RequestHandler {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead || m.preview {
+			handler(w, r)
+			return
+		}
+		url := r.URL.Path
+		if res, err := m.core.StatObject(url); err != nil || !isDocument(res) {
+			handler(w, r)
+			return
+		}
+		res, err := m.core.GetObject(url)
+		if closer, ok := res.Data.(io.Closer); ok {
+			defer closer.Close()
+		}
+		if err == nil && res.Data != nil && readsDraft(res.Data) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		handler(w, r)
+	}
 }
 
-// RenderMarkdown decorates a Serve function to render and return a HTML
-// resource from a markdown resource.
-func (m Markdown) RenderMarkdown(Serve ServeHandler) ServeHandler {
+// RenderDocument decorates a Serve function to render and return a HTML
+// resource from a document resource (e.g. markdown, AsciiDoc or RST) with the
+// renderer of its extension or content type.
+func (m Markdown) RenderDocument(Serve ServeHandler) ServeHandler {
 
 	return func(w http.ResponseWriter, resource Resource) error {
-		if !isMarkdown(resource) {
+		renderer := documentRenderer(resource)
+		if renderer == nil {
 			return Serve(w, resource)
 		}
 
@@ -73,15 +219,20 @@ func (m Markdown) RenderMarkdown(Serve ServeHandler) ServeHandler {
 			return Serve(w, resource)
 		}
 
-		rendered := m.md.RenderToString(content)
-		var buf bytes.Buffer
-		err = m.template.Execute(&buf, TemplateData{Content: template.HTML(rendered)})
+		frontMatter, body := parseFrontMatter(content)
+		if frontMatter.Draft && !m.preview {
+			w.Header().Del("Content-Type")
+			w.Header().Del("Content-Length")
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return nil
+		}
+		body, err = renderer(body)
 		if err != nil {
 			return err
 		}
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		w.Header().Set("Content-Length", strconv.FormatInt(int64(len(buf.Bytes())), 10))
-		_, err = w.Write(buf.Bytes())
-		return err
+
+		url := m.resourceURL(resource.Info)
+		rendered, toc, assets := m.render(body, url)
+		return m.serve(w, url, TemplateData{Content: template.HTML(rendered), TOC: toc, Assets: assets, FrontMatter: frontMatter})
 	}
 }
@@ -0,0 +1,219 @@
+package ext
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/chroma/lexers"
+)
+
+// notebookAssets are the styles of the rendered notebooks.
+const notebookAssets = `<style>
+  .nb-cell { margin-bottom: 16px; }
+  .nb-prompt { color: #6a737d; font-family: monospace; font-size: 12px; }
+  .nb-output { border-left: 3px solid #eaecef; padding-left: 12px; overflow-x: auto; }
+  .nb-output pre { background-color: transparent; }
+  .nb-output img { max-width: 100%; }
+  .nb-stderr, .nb-error { background-color: #fff5f5 !important; }
+</style>
+`
+
+// notebookText is a text of a notebook, either as a string or as a list of
+// lines.
+type notebookText string
+
+// UnmarshalJSON unmarshals a string or a list of lines.
+func (t *notebookText) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*t = notebookText(strings.Join(lines, ""))
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	*t = notebookText(text)
+	return nil
+}
+
+// notebook is a jupyter notebook (nbformat 4).
+type notebook struct {
+	Cells    []notebookCell `json:"cells"`
+	Metadata struct {
+		Title      string `json:"title"`
+		KernelSpec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+// notebookCell is a markdown, code or raw cell of a notebook.
+type notebookCell struct {
+	CellType       string           `json:"cell_type"`
+	Source         notebookText     `json:"source"`
+	ExecutionCount *int             `json:"execution_count"`
+	Outputs        []notebookOutput `json:"outputs"`
+}
+
+// notebookOutput is an output of a code cell.
+type notebookOutput struct {
+	OutputType string       `json:"output_type"`
+	Name       string       `json:"name"`
+	Text       notebookText `json:"text"`
+	// mime bundle, only the rendered mime types are decoded (others can be
+	// JSON objects, e.g. application/json)
+	Data           map[string]json.RawMessage `json:"data"`
+	ExecutionCount *int                       `json:"execution_count"`
+	EName          string                     `json:"ename"`
+	EValue         string                     `json:"evalue"`
+	Traceback      []string                   `json:"traceback"`
+}
+
+// ansiEscapes are the colors of the tracebacks.
+var ansiEscapes = regexp.MustCompile("\x1b\\[[0-9;]*[A-Za-z]")
+
+// language returns the language of the code cells (default: python).
+func (nb notebook) language() string {
+	switch {
+	case nb.Metadata.LanguageInfo.Name != "":
+		return nb.Metadata.LanguageInfo.Name
+	case nb.Metadata.KernelSpec.Language != "":
+		return nb.Metadata.KernelSpec.Language
+	}
+	return "python"
+}
+
+// prompt returns the prompt of a cell (e.g. In [1]:).
+func prompt(label string, count *int) string {
+	if count == nil {
+		return fmt.Sprintf(`<div class="nb-prompt">%s [ ]:</div>`, label)
+	}
+	return fmt.Sprintf(`<div class="nb-prompt">%s [%d]:</div>`, label, *count)
+}
+
+// isNotebook checks whether a resource is a jupyter notebook.
+func isNotebook(resource Resource) bool {
+	return strings.Contains(strings.ToLower(resource.Info.ContentType), "ipynb") || strings.HasSuffix(strings.ToLower(resource.Info.Key), ".ipynb")
+}
+
+// mimeText decodes the text of a mime type of the output if any.
+func (output notebookOutput) mimeText(mimeType string) (notebookText, bool) {
+	var text notebookText
+	data, ok := output.Data[mimeType]
+	if !ok || json.Unmarshal(data, &text) != nil {
+		return "", false
+	}
+	return text, true
+}
+
+// renderNotebookOutput renders an output of a code cell as HTML. The rich
+// outputs are rendered as HTML, png or jpeg images, or text (in this order).
+func renderNotebookOutput(buf *bytes.Buffer, output notebookOutput) {
+	switch output.OutputType {
+	case "stream":
+		fmt.Fprintf(buf, `<pre class="nb-%s">%s</pre>`, template.HTMLEscapeString(output.Name), template.HTMLEscapeString(string(output.Text)))
+	case "error":
+		traceback := ansiEscapes.ReplaceAllString(strings.Join(output.Traceback, "\n"), "")
+		if traceback == "" {
+			traceback = output.EName + ": " + output.EValue
+		}
+		fmt.Fprintf(buf, `<pre class="nb-error">%s</pre>`, template.HTMLEscapeString(traceback))
+	case "execute_result", "display_data":
+		if html, ok := output.mimeText("text/html"); ok {
+			fmt.Fprintf(buf, `<div class="nb-html">%s</div>`, html)
+			return
+		}
+		for _, image := range []string{"image/png", "image/jpeg"} {
+			if data, ok := output.mimeText(image); ok {
+				fmt.Fprintf(buf, `<img src="data:%s;base64,%s" />`, image, template.HTMLEscapeString(strings.Join(strings.Fields(string(data)), "")))
+				return
+			}
+		}
+		if text, ok := output.mimeText("text/plain"); ok {
+			fmt.Fprintf(buf, `<pre>%s</pre>`, template.HTMLEscapeString(string(text)))
+		}
+	}
+}
+
+// renderNotebook renders the cells of a notebook at the url as HTML, and
+// returns its table of contents and the assets of its cells.
+func (m Markdown) renderNotebook(nb notebook, url string) (string, TOC, template.HTML) {
+	anchors := newHeadingAnchors(m.tocDepth)
+	assets := &pageAssets{}
+	assets.add(notebookAssets)
+	lexer := lexers.Get(nb.language())
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+
+	var buf bytes.Buffer
+	for _, cell := range nb.Cells {
+		switch cell.CellType {
+		case "markdown":
+			rendered := m.renderPart([]byte(cell.Source), url, anchors, assets)
+			fmt.Fprintf(&buf, `<div class="nb-cell nb-markdown">%s</div>`, rendered)
+		case "code":
+			buf.WriteString(`<div class="nb-cell nb-code">`)
+			buf.WriteString(prompt("In", cell.ExecutionCount))
+			highlighted, err := m.fences.highlighter.highlight(string(cell.Source), lexer)
+			if err != nil {
+				highlighted = fmt.Sprintf("<pre>%s</pre>", template.HTMLEscapeString(string(cell.Source)))
+			}
+			buf.WriteString(highlighted)
+			for _, output := range cell.Outputs {
+				if output.OutputType == "execute_result" {
+					buf.WriteString(prompt("Out", output.ExecutionCount))
+				}
+				buf.WriteString(`<div class="nb-output">`)
+				renderNotebookOutput(&buf, output)
+				buf.WriteString(`</div>`)
+			}
+			buf.WriteString(`</div>`)
+		case "raw":
+			fmt.Fprintf(&buf, `<div class="nb-cell nb-raw"><pre>%s</pre></div>`, template.HTMLEscapeString(string(cell.Source)))
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.String(), anchors.toc, assets.HTML()
+}
+
+// RenderNotebook decorates a Serve function to render and return a HTML
+// resource from a jupyter notebook resource.
+func (m Markdown) RenderNotebook(Serve ServeHandler) ServeHandler {
+
+	return func(w http.ResponseWriter, resource Resource) error {
+		if !isNotebook(resource) {
+			return Serve(w, resource)
+		}
+
+		content, err := ioutil.ReadAll(resource.Data)
+		resource.Data = bytes.NewReader(content)
+		if err != nil {
+			return Serve(w, resource)
+		}
+		var nb notebook
+		if err := json.Unmarshal(content, &nb); err != nil {
+			// not a valid notebook
+			return Serve(w, resource)
+		}
+
+		url := m.resourceURL(resource.Info)
+		rendered, toc, assets := m.renderNotebook(nb, url)
+		frontMatter := FrontMatter{Title: nb.Metadata.Title}
+		if frontMatter.Title == "" {
+			frontMatter.Title = strings.TrimSuffix(path.Base(resource.Info.Key), path.Ext(resource.Info.Key))
+		}
+		return m.serve(w, url, TemplateData{Content: template.HTML(rendered), TOC: toc, Assets: assets, FrontMatter: frontMatter})
+	}
+}
@@ -0,0 +1,128 @@
+package ext
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testNotebook is a notebook with the texts as strings or lists of lines, and
+// the outputs of each type.
+const testNotebook = `{
+  "metadata": {"title": "Report", "language_info": {"name": "python"}},
+  "cells": [
+    {"cell_type": "markdown", "source": ["# Intro\n", "Some *text*"]},
+    {"cell_type": "code", "execution_count": 1, "source": "print('hello')", "outputs": [
+      {"output_type": "stream", "name": "stdout", "text": ["hello\n", "world"]},
+      {"output_type": "execute_result", "execution_count": 1, "data": {
+        "text/html": ["<table>", "</table>"], "image/png": "aW1n", "text/plain": "table"}},
+      {"output_type": "display_data", "data": {"image/png": "aW1n\nZw==\n", "image/jpeg": "anBn", "text/plain": "png"}},
+      {"output_type": "display_data", "data": {"image/jpeg": "anBn", "text/plain": "jpeg"}},
+      {"output_type": "display_data", "data": {"text/plain": ["<b>", "plain"], "application/json": {"a": 1}}},
+      {"output_type": "error", "ename": "ValueError", "evalue": "bad", "traceback": ["\u001b[0;31mValueError\u001b[0m: bad"]},
+      {"output_type": "error", "ename": "KeyError", "evalue": "x", "traceback": []}
+    ]},
+    {"cell_type": "raw", "source": "<raw>"}
+  ]
+}`
+
+func TestRenderNotebook(t *testing.T) {
+	templateFile := filepath.Join(t.TempDir(), "template.html")
+	if err := ioutil.WriteFile(templateFile, []byte("{{.Title}}\n{{.Content}}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewMarkdown(nil, templateFile, MarkdownConfig{Notebooks: true}, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve := m.RenderNotebook(func(w http.ResponseWriter, resource Resource) error {
+		content, _ := ioutil.ReadAll(resource.Data)
+		_, err := w.Write(append([]byte("served: "), content...))
+		return err
+	})
+
+	tests := []struct {
+		name     string
+		key      string
+		content  string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:    "notebook",
+			key:     "docs/report.ipynb",
+			content: testNotebook,
+			contains: []string{
+				"Report\n",
+				"Intro</h1>",
+				"<em>text</em>",
+				`<div class="nb-prompt">In [1]:</div>`,
+				`<pre class="nb-stdout">hello
+world</pre>`,
+				`<div class="nb-prompt">Out [1]:</div><div class="nb-output"><div class="nb-html"><table></table></div></div>`,
+				`<div class="nb-output"><img src="data:image/png;base64,aW1nZw==" /></div>`,
+				`<div class="nb-output"><img src="data:image/jpeg;base64,anBn" /></div>`,
+				`<div class="nb-output"><pre>&lt;b&gt;plain</pre></div>`,
+				`<pre class="nb-error">ValueError: bad</pre>`,
+				`<pre class="nb-error">KeyError: x</pre>`,
+				`<div class="nb-cell nb-raw"><pre>&lt;raw&gt;</pre></div>`,
+			},
+			excludes: []string{"served:", "\x1b", "base64,aW1n\"", "<pre>table</pre>", "<pre>png</pre>", "<pre>jpeg</pre>"},
+		},
+		{
+			name:     "invalid notebook",
+			key:      "docs/report.ipynb",
+			content:  `{"cells": [`,
+			contains: []string{`served: {"cells": [`},
+		},
+		{
+			name:     "not a notebook",
+			key:      "docs/report.json",
+			content:  testNotebook,
+			contains: []string{"served: " + testNotebook},
+		},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		resource := Resource{Data: bytes.NewReader([]byte(test.content)), Info: ResourceInfo{Key: test.key}}
+		if err := serve(w, resource); err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		body := w.Body.String()
+		for _, s := range test.contains {
+			if !strings.Contains(body, s) {
+				t.Errorf("%s: expected %q in %q", test.name, s, body)
+			}
+		}
+		for _, s := range test.excludes {
+			if strings.Contains(body, s) {
+				t.Errorf("%s: unexpected %q in %q", test.name, s, body)
+			}
+		}
+	}
+}
+
+func TestNotebookText(t *testing.T) {
+	tests := []struct {
+		json string
+		text string
+		err  bool
+	}{
+		{json: `"a\nb"`, text: "a\nb"},
+		{json: `["a\n", "b"]`, text: "a\nb"},
+		{json: `[]`, text: ""},
+		{json: `1`, err: true},
+	}
+	for _, test := range tests {
+		var text notebookText
+		err := text.UnmarshalJSON([]byte(test.json))
+		if (err != nil) != test.err || string(text) != test.text {
+			t.Errorf("%s: expected %q (error: %t), got %q (%v)", test.json, test.text, test.err, text, err)
+		}
+	}
+}
@@ -0,0 +1,440 @@
+package ext
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// oidcSessionCookie holds the id token of the logged in user.
+	oidcSessionCookie = "minio-web-session"
+	// oidcStateCookie holds the state of an ongoing login.
+	oidcStateCookie = "minio-web-oidc-state"
+	// oidcLogoutPath clears the session of the logged in user.
+	oidcLogoutPath = "/_auth/logout"
+	// oidcDefaultCallbackPath is used if no redirect url is provided.
+	oidcDefaultCallbackPath = "/_auth/callback"
+)
+
+// OIDCConfig is used to config the OpenID Connect / JWT authentication
+// extension.
+type OIDCConfig struct {
+	// Issuer of the tokens. Endpoints which are not provided are discovered
+	// from the issuer.
+	Issuer string `json:"issuer"`
+	// Client credentials for the authorization code flow. The login flow is
+	// only enabled if a client id is provided, otherwise only bearer tokens
+	// are accepted.
+	ClientID     string `json:"clientid"`
+	ClientSecret string `json:"clientsecret"`
+	// Url of the callback (e.g. https://minio-web/_auth/callback).
+	RedirectURL string `json:"redirecturl"`
+	// Comma separated list of scopes (default: openid,email,profile).
+	Scopes string `json:"scopes"`
+	// Endpoints of the identity provider.
+	AuthURL  string `json:"authurl"`
+	TokenURL string `json:"tokenurl"`
+	JWKSURL  string `json:"jwksurl"`
+	// Local JWKS file to verify tokens with (e.g. for a stub issuer).
+	JWKSFile string `json:"jwksfile"`
+	// Expected audience of the tokens (default: client id).
+	Audience string `json:"audience"`
+	// Claim holding the groups of the user (default: groups).
+	GroupsClaim string `json:"groupsclaim"`
+	// Secret to sign the login state with (random if not provided).
+	SessionSecret string `json:"sessionsecret"`
+	// Comma separated list of url prefixes which do not require
+	// authentication (e.g. health endpoints).
+	Exempt string `json:"exempt"`
+}
+
+// OIDC provides the decorator to authenticate requests with bearer JWTs or
+// with an OpenID Connect login flow.
+type OIDC struct {
+	config       OIDCConfig
+	jwks         *JWKS
+	scopes       []string
+	exempt       []string
+	callbackPath string
+	secret       []byte
+	client       *http.Client
+}
+
+// oidcDiscovery is the OpenID provider metadata.
+type oidcDiscovery struct {
+	AuthURL  string `json:"authorization_endpoint"`
+	TokenURL string `json:"token_endpoint"`
+	JWKSURL  string `json:"jwks_uri"`
+}
+
+// OIDCExtension installs the extension to authenticate requests with bearer
+// JWTs or an OpenID Connect login flow.
+func OIDCExtension(config OIDCConfig) Extension {
+	return func(c *Core) (string, error) {
+		if config.Issuer == "" && config.JWKSFile == "" && config.JWKSURL == "" {
+			return "oidc: disabled", nil
+		}
+		oidc, err := NewOIDC(config)
+		if err != nil {
+			return "oidc: errored", err
+		}
+		c.ApplyAccess(oidc.Authenticate)
+		return fmt.Sprintf("oidc: %s (login flow: %t)", config.Issuer, oidc.loginEnabled()), nil
+	}
+}
+
+// NewOIDC creates a new OIDC object.
+func NewOIDC(config OIDCConfig) (*OIDC, error) {
+	oidc := &OIDC{
+		config:       config,
+		scopes:       splitList(config.Scopes),
+		exempt:       splitList(config.Exempt),
+		callbackPath: oidcDefaultCallbackPath,
+		secret:       []byte(config.SessionSecret),
+		client:       &http.Client{Timeout: 10 * time.Second}}
+
+	if len(oidc.scopes) == 0 {
+		oidc.scopes = []string{"openid", "email", "profile"}
+	}
+	if oidc.config.Audience == "" {
+		oidc.config.Audience = config.ClientID
+	}
+	if oidc.config.GroupsClaim == "" {
+		oidc.config.GroupsClaim = "groups"
+	}
+	if config.RedirectURL != "" {
+		u, err := url.Parse(config.RedirectURL)
+		if err != nil {
+			return nil, err
+		}
+		oidc.callbackPath = u.Path
+	}
+	if len(oidc.secret) == 0 {
+		oidc.secret = make([]byte, 32)
+		if _, err := rand.Read(oidc.secret); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := oidc.discover(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if config.JWKSFile != "" {
+		oidc.jwks, err = NewJWKSFromFile(config.JWKSFile)
+	} else if oidc.config.JWKSURL != "" {
+		oidc.jwks, err = NewJWKSFromURL(oidc.config.JWKSURL)
+	} else {
+		err = errors.New("oidc: no JWKS provided or discovered")
+	}
+	return oidc, err
+}
+
+// discover retrieves the endpoints which are not provided from the issuer.
+func (o *OIDC) discover() error {
+	needJWKS := o.config.JWKSFile == "" && o.config.JWKSURL == ""
+	needLogin := o.config.ClientID != "" && (o.config.AuthURL == "" || o.config.TokenURL == "")
+	if o.config.Issuer == "" || (!needJWKS && !needLogin) {
+		return nil
+	}
+
+	resp, err := o.client.Get(strings.TrimSuffix(o.config.Issuer, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc discovery[%s]: %s", o.config.Issuer, resp.Status)
+	}
+	var discovery oidcDiscovery
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+		return err
+	}
+	if o.config.AuthURL == "" {
+		o.config.AuthURL = discovery.AuthURL
+	}
+	if o.config.TokenURL == "" {
+		o.config.TokenURL = discovery.TokenURL
+	}
+	if o.config.JWKSURL == "" {
+		o.config.JWKSURL = discovery.JWKSURL
+	}
+	return nil
+}
+
+// loginEnabled checks whether the authorization code flow can be used.
+func (o *OIDC) loginEnabled() bool {
+	return o.config.ClientID != "" && o.config.AuthURL != "" && o.config.TokenURL != ""
+}
+
+// verify verifies the token and returns its claims.
+func (o *OIDC) verify(token string) (Claims, error) {
+	claims, err := o.jwks.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	if o.config.Issuer != "" && claims.Value("iss") != o.config.Issuer {
+		return nil, fmt.Errorf("invalid issuer: %s", claims.Value("iss"))
+	}
+	if o.config.Audience != "" {
+		for _, aud := range claims.Values("aud") {
+			if aud == o.config.Audience {
+				return claims, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid audience: %v", claims.Values("aud"))
+	}
+	return claims, nil
+}
+
+// identity creates an Identity from the claims of a verified token.
+func (o *OIDC) identity(claims Claims) Identity {
+	name := claims.Value("preferred_username")
+	if name == "" {
+		name = claims.Value("email")
+	}
+	if name == "" {
+		name = claims.Value("sub")
+	}
+	return Identity{
+		Name:     name,
+		Provider: "oidc",
+		Email:    claims.Value("email"),
+		Groups:   claims.Values(o.config.GroupsClaim)}
+}
+
+// getToken returns the bearer token or the session token of the request.
+func getToken(r *http.Request) (token string, bearer bool) {
+	if auth := r.Header.Get("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		return strings.TrimSpace(auth[7:]), true
+	}
+	if cookie, err := r.Cookie(oidcSessionCookie); err == nil {
+		return cookie.Value, false
+	}
+	return "", false
+}
+
+// Authenticate decorates a RequestHandler to require a valid bearer token or
+// session, and redirects browsers to the login flow if needed.
+func (o *OIDC) Authenticate(handler RequestHandler) RequestHandler {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		switch {
+		case o.loginEnabled() && path == o.callbackPath:
+			o.callback(w, r)
+			return
+		case path == oidcLogoutPath:
+			o.setCookie(w, r, oidcSessionCookie, "", time.Unix(0, 0))
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		case hasAnyPrefix(path, o.exempt):
+			handler(w, r)
+			return
+		}
+		// requests can already be authenticated (e.g. signed urls)
+		if _, ok := GetIdentity(r); ok {
+			handler(w, r)
+			return
+		}
+
+		token, bearer := getToken(r)
+		if token != "" {
+			identity, ok := authenticate(r, "oidc:"+token, func() (Identity, bool) {
+				claims, err := o.verify(token)
+				if err != nil {
+					return Identity{}, false
+				}
+				return o.identity(claims), true
+			})
+			if ok {
+				handler(w, WithIdentity(r, identity))
+				return
+			}
+		}
+
+		if o.loginEnabled() && !bearer && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+			o.login(w, r)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="minio-web"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+}
+
+// login redirects the browser to the authorization endpoint.
+func (o *OIDC) login(w http.ResponseWriter, r *http.Request) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	state := hex.EncodeToString(nonce)
+	o.setCookie(w, r, oidcStateCookie, o.sign(state+"|"+r.URL.RequestURI()), time.Now().Add(10*time.Minute))
+
+	query := url.Values{
+		"response_type": {"code"},
+		"client_id":     {o.config.ClientID},
+		"redirect_uri":  {o.redirectURL(r)},
+		"scope":         {strings.Join(o.scopes, " ")},
+		"state":         {state},
+		"nonce":         {state}}
+	sep := "?"
+	if strings.Contains(o.config.AuthURL, "?") {
+		sep = "&"
+	}
+	http.Redirect(w, r, o.config.AuthURL+sep+query.Encode(), http.StatusFound)
+}
+
+// isLocalURL checks whether a return url is a path of the server, i.e. not
+// an absolute url or a network-path reference (//host, or /\host which is
+// treated as such by the browsers).
+func isLocalURL(returnURL string) bool {
+	if !strings.HasPrefix(returnURL, "/") || strings.HasPrefix(returnURL, "//") || strings.HasPrefix(returnURL, "/\\") {
+		return false
+	}
+	u, err := url.Parse(returnURL)
+	return err == nil && u.Scheme == "" && u.Host == ""
+}
+
+// callback exchanges the authorization code for an id token and starts a
+// session.
+func (o *OIDC) callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if e := query.Get("error"); e != "" {
+		http.Error(w, fmt.Sprintf("login failed: %s", e), http.StatusUnauthorized)
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil {
+		http.Error(w, "login failed: missing state", http.StatusBadRequest)
+		return
+	}
+	value, ok := o.unsign(cookie.Value)
+	parts := strings.SplitN(value, "|", 2)
+	if !ok || len(parts) != 2 || parts[0] != query.Get("state") {
+		http.Error(w, "login failed: invalid state", http.StatusBadRequest)
+		return
+	}
+	state, returnURL := parts[0], parts[1]
+	// only redirect to a local url
+	if !isLocalURL(returnURL) {
+		returnURL = "/"
+	}
+
+	idToken, err := o.exchange(query.Get("code"), o.redirectURL(r))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("login failed: %v", err), http.StatusUnauthorized)
+		return
+	}
+	claims, err := o.verify(idToken)
+	if err != nil || claims.Value("nonce") != state {
+		http.Error(w, "login failed: invalid id token", http.StatusUnauthorized)
+		return
+	}
+
+	expires, ok := claims.time("exp")
+	if !ok {
+		expires = time.Now().Add(1 * time.Hour)
+	}
+	o.setCookie(w, r, oidcStateCookie, "", time.Unix(0, 0))
+	o.setCookie(w, r, oidcSessionCookie, idToken, expires)
+	http.Redirect(w, r, returnURL, http.StatusFound)
+}
+
+// exchange exchanges an authorization code for an id token.
+func (o *OIDC) exchange(code string, redirectURL string) (string, error) {
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {redirectURL}}
+	req, err := http.NewRequest(http.MethodPost, o.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(o.config.ClientID), url.QueryEscape(o.config.ClientSecret))
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint: %s", resp.Status)
+	}
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return "", err
+	}
+	if tokens.IDToken == "" {
+		return "", errors.New("token endpoint: no id token")
+	}
+	return tokens.IDToken, nil
+}
+
+// redirectURL returns the callback url.
+func (o *OIDC) redirectURL(r *http.Request) string {
+	if o.config.RedirectURL != "" {
+		return o.config.RedirectURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, r.Host, o.callbackPath)
+}
+
+// setCookie sets (or clears if expired) a http only cookie.
+func (o *OIDC) setCookie(w http.ResponseWriter, r *http.Request, name string, value string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || strings.HasPrefix(o.config.RedirectURL, "https://"),
+		SameSite: http.SameSiteLaxMode})
+}
+
+// sign appends a HMAC signature to the value.
+func (o *OIDC) sign(value string) string {
+	mac := hmac.New(sha256.New, o.secret)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString([]byte(value)) + "." +
+		base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// unsign verifies the HMAC signature of a signed value.
+func (o *OIDC) unsign(signed string) (string, bool) {
+	parts := strings.SplitN(signed, ".", 2)
+	if len(parts) != 2 {
+		return "", false
+	}
+	value, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", false
+	}
+	mac := hmac.New(sha256.New, o.secret)
+	mac.Write(value)
+	return string(value), hmac.Equal(signature, mac.Sum(nil))
+}
@@ -0,0 +1,60 @@
+package ext
+
+import (
+	"crypto"
+	"testing"
+	"time"
+)
+
+func TestVerifyAudience(t *testing.T) {
+	keys := newTestKeys(t)
+	o := &OIDC{
+		config: OIDCConfig{Issuer: "https://issuer", Audience: "minio-web"},
+		jwks:   &JWKS{keys: map[string]crypto.PublicKey{"k1": &keys.rsa.PublicKey}}}
+
+	tests := []struct {
+		name   string
+		claims Claims
+		valid  bool
+	}{
+		{"audience", Claims{"iss": "https://issuer", "aud": "minio-web"}, true},
+		{"audience in list", Claims{"iss": "https://issuer", "aud": []string{"other", "minio-web"}}, true},
+		{"other audience", Claims{"iss": "https://issuer", "aud": "other"}, false},
+		{"other audiences", Claims{"iss": "https://issuer", "aud": []string{"other", "minio-web-2"}}, false},
+		{"no audience", Claims{"iss": "https://issuer"}, false},
+		{"other issuer", Claims{"iss": "https://other", "aud": "minio-web"}, false},
+	}
+	for _, test := range tests {
+		test.claims["exp"] = time.Now().Add(time.Hour).Unix()
+		_, err := o.verify(keys.token(t, "RS256", test.claims))
+		if test.valid && err != nil {
+			t.Errorf("%s: expected a valid token, got %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: expected an invalid token", test.name)
+		}
+	}
+}
+
+func TestIsLocalURL(t *testing.T) {
+	tests := map[string]bool{
+		"/":                      true,
+		"/docs/a.md?raw=1#top":   true,
+		"/docs//a.md":            true,
+		"":                       false,
+		"docs/a.md":              false,
+		"//evil.com":             false,
+		"/\\evil.com":            false,
+		"/\\/evil.com":           false,
+		"https://evil.com":       false,
+		"/\t/evil.com":           false,
+		"javascript:alert(1)":    false,
+		"\\\\evil.com":           false,
+		"https:/\\evil.com/a.md": false,
+	}
+	for returnURL, local := range tests {
+		if isLocalURL(returnURL) != local {
+			t.Errorf("%q: expected local %t", returnURL, local)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package ext
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	glob "github.com/gobwas/glob"
+)
+
+// PresignConfig is used to config the redirect to presigned url extension.
+type PresignConfig struct {
+	// Objects larger than the size (bytes) are redirected (0 to disable).
+	MinSize int64 `json:"minsize"`
+	// Comma separated list of globs for object keys which are always
+	// redirected (e.g. **.{zip,tar.gz}).
+	Objects string `json:"objects"`
+	// Number of seconds the presigned url is valid for (default: 300).
+	Expiry int `json:"expiry"`
+}
+
+// Presign provides the decorator to redirect to presigned urls instead of
+// proxying large objects.
+type Presign struct {
+	helper  *MinioHelper
+	minSize int64
+	objects []glob.Glob
+	expiry  time.Duration
+}
+
+// PresignExtension installs the extension to redirect to a short-lived
+// presigned url for large objects. Must be applied before the renderers (e.g.
+// markdown) so that rendered objects are still proxied.
+func PresignExtension(helper *MinioHelper, config PresignConfig) Extension {
+	return func(c *Core) (string, error) {
+		if config.MinSize <= 0 && config.Objects == "" {
+			return "presigned redirect: disabled", nil
+		}
+		presign, err := NewPresign(helper, config)
+		if err != nil {
+			return "presigned redirect: errored", err
+		}
+		c.ApplyServe(presign.RedirectToPresignedURL)
+		return fmt.Sprintf("presigned redirect: > %d bytes or %s", config.MinSize, config.Objects), nil
+	}
+}
+
+// NewPresign creates a new Presign object.
+func NewPresign(helper *MinioHelper, config PresignConfig) (*Presign, error) {
+	presign := &Presign{
+		helper:  helper,
+		minSize: config.MinSize,
+		expiry:  time.Duration(config.Expiry) * time.Second}
+	if presign.expiry <= 0 {
+		presign.expiry = 5 * time.Minute
+	}
+	for _, pattern := range splitList(config.Objects) {
+		g, err := glob.Compile(pattern, '/')
+		if err != nil {
+			return nil, err
+		}
+		presign.objects = append(presign.objects, g)
+	}
+	return presign, nil
+}
+
+// shouldRedirect checks whether a resource should be redirected.
+func (p *Presign) shouldRedirect(info ResourceInfo) bool {
+	// only objects from the backend can be presigned
+	if info.Bucket == "" || info.Key == "" {
+		return false
+	}
+	if p.minSize > 0 && info.Size > p.minSize {
+		return true
+	}
+	for _, g := range p.objects {
+		if g.Match(info.Key) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedirectToPresignedURL decorates a Serve function to redirect to a presigned
+// url instead of streaming the resource.
+func (p *Presign) RedirectToPresignedURL(Serve ServeHandler) ServeHandler {
+
+	return func(w http.ResponseWriter, resource Resource) error {
+		if !p.shouldRedirect(resource.Info) {
+			return Serve(w, resource)
+		}
+
+		u, err := p.helper.PresignedGetObject(resource.Info.Bucket, resource.Info.Key, p.expiry)
+		if err != nil {
+			return Serve(w, resource)
+		}
+		// object is not streamed
+		if closer, ok := resource.Data.(io.Closer); ok {
+			closer.Close()
+		}
+		w.Header().Del("Content-Length")
+		w.Header().Del("Content-Type")
+		w.Header().Set("Location", u)
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusFound)
+		return nil
+	}
+}
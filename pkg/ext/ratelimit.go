@@ -0,0 +1,247 @@
+package ext
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bluele/gcache"
+)
+
+// RateLimitConfig is used to config the rate limiting extension.
+type RateLimitConfig struct {
+	// Number of requests allowed per minute for each key (0 to disable).
+	Rate int `json:"rate"`
+	// Max number of requests allowed in a burst (default: rate).
+	Burst int `json:"burst"`
+	// Requests are limited by client ip (ip), authenticated identity
+	// (identity) or url prefix (prefix). Default: ip.
+	Key string `json:"key"`
+	// Number of path segments of the url prefix key (default: 1).
+	PrefixDepth int `json:"prefixdepth"`
+	// Max number of concurrent GET requests streaming from the backend
+	// (0 for unlimited).
+	MaxConcurrent int `json:"maxconcurrent"`
+}
+
+// RateLimit provides the decorator to limit the rate of requests and the
+// number of concurrent downloads.
+type RateLimit struct {
+	config   RateLimitConfig
+	resolver *ClientIPResolver
+	// token bucket for each key
+	buckets gcache.Cache
+	mutex   sync.Mutex
+	// semaphore for concurrent downloads
+	streams chan struct{}
+}
+
+// tokenBucket holds the tokens available for a key.
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	mutex     sync.Mutex
+}
+
+// RateLimitExtension installs the extension to limit the rate of requests by
+// identity and the number of concurrent downloads. Must be applied before the
+// authentication extensions to limit by identity.
+func RateLimitExtension(config RateLimitConfig, trustedProxies string) Extension {
+	return func(c *Core) (string, error) {
+		if config.Rate <= 0 && config.MaxConcurrent <= 0 {
+			return "rate limit: disabled", nil
+		}
+		limiter, err := NewRateLimit(config, trustedProxies)
+		if err != nil {
+			return "rate limit: errored", err
+		}
+		c.ApplyRequest(limiter.Limit)
+		return fmt.Sprintf("rate limit: %d/min per %s, %d concurrent", config.Rate, limiter.config.Key, config.MaxConcurrent), nil
+	}
+}
+
+// ClientRateLimitExtension installs the extension to limit the rate of
+// requests by client ip or url prefix, or the rate of failed authentications
+// by client ip when limiting by identity. Must be applied after the
+// authentication extensions so that the requests are limited before being
+// authenticated.
+func ClientRateLimitExtension(config RateLimitConfig, trustedProxies string) Extension {
+	return func(c *Core) (string, error) {
+		if config.Rate <= 0 {
+			return "client rate limit: disabled", nil
+		}
+		limiter, err := NewRateLimit(config, trustedProxies)
+		if err != nil {
+			return "client rate limit: errored", err
+		}
+		c.ApplyRequest(limiter.LimitClients)
+		if limiter.config.Key == "identity" {
+			return fmt.Sprintf("client rate limit: %d/min failed authentications per ip", config.Rate), nil
+		}
+		return fmt.Sprintf("client rate limit: %d/min per %s", config.Rate, limiter.config.Key), nil
+	}
+}
+
+// NewRateLimit creates a new RateLimit object.
+func NewRateLimit(config RateLimitConfig, trustedProxies string) (*RateLimit, error) {
+	resolver, err := NewClientIPResolver(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	switch config.Key {
+	case "":
+		config.Key = "ip"
+	case "ip", "identity", "prefix":
+	default:
+		return nil, fmt.Errorf("rate limit: unknown key %q", config.Key)
+	}
+	if config.Burst <= 0 {
+		config.Burst = config.Rate
+	}
+	if config.PrefixDepth <= 0 {
+		config.PrefixDepth = 1
+	}
+
+	limiter := &RateLimit{
+		config:   config,
+		resolver: resolver,
+		buckets:  gcache.New(10000).LRU().Build()}
+	if config.MaxConcurrent > 0 {
+		limiter.streams = make(chan struct{}, config.MaxConcurrent)
+	}
+	return limiter, nil
+}
+
+// key returns the key to limit the request by.
+func (l *RateLimit) key(r *http.Request) string {
+	switch l.config.Key {
+	case "identity":
+		if identity, ok := GetIdentity(r); ok && identity.Name != "" {
+			return "identity:" + identity.Provider + ":" + identity.Name
+		}
+	case "prefix":
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", l.config.PrefixDepth+1)
+		if len(parts) > l.config.PrefixDepth {
+			parts = parts[:l.config.PrefixDepth]
+		}
+		return "prefix:/" + strings.Join(parts, "/")
+	}
+	// anonymous requests are limited by ip
+	return l.clientKey(r)
+}
+
+// clientKey returns the key of the client ip of the request.
+func (l *RateLimit) clientKey(r *http.Request) string {
+	return "ip:" + l.resolver.ClientIP(r).String()
+}
+
+// take takes a token from the bucket of the key (or only checks that a token
+// is available if not consume), and returns the duration to wait for the next
+// token if none is available.
+func (l *RateLimit) take(key string, consume bool) (bool, time.Duration) {
+	l.mutex.Lock()
+	unknown, err := l.buckets.Get(key)
+	if err != nil {
+		unknown = &tokenBucket{tokens: float64(l.config.Burst), updatedAt: time.Now()}
+		l.buckets.Set(key, unknown)
+	}
+	l.mutex.Unlock()
+	bucket := unknown.(*tokenBucket)
+
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+	perSecond := float64(l.config.Rate) / 60
+	now := time.Now()
+	bucket.tokens = math.Min(float64(l.config.Burst), bucket.tokens+now.Sub(bucket.updatedAt).Seconds()*perSecond)
+	bucket.updatedAt = now
+	if bucket.tokens >= 1 {
+		if consume {
+			bucket.tokens--
+		}
+		return true, 0
+	}
+	return false, time.Duration((1 - bucket.tokens) / perSecond * float64(time.Second))
+}
+
+// tooManyRequests responds with 429 and the number of seconds to wait.
+func tooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	w.WriteHeader(http.StatusTooManyRequests)
+}
+
+// statusRecorder records the status code of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// Unwrap returns the recorded http.ResponseWriter.
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *statusRecorder) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// LimitClients decorates a RequestHandler to reject requests exceeding the
+// rate limit of their client ip or url prefix before they are authenticated.
+// When limiting by identity, only the failed authentications (401 or 403) are
+// counted against the client ip, so that guessing credentials is limited.
+func (l *RateLimit) LimitClients(handler RequestHandler) RequestHandler {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.config.Key != "identity" {
+			if ok, wait := l.take(l.key(r), true); !ok {
+				tooManyRequests(w, wait)
+				return
+			}
+			handler(w, r)
+			return
+		}
+
+		key := l.clientKey(r)
+		if ok, wait := l.take(key, false); !ok {
+			tooManyRequests(w, wait)
+			return
+		}
+		recorder := &statusRecorder{ResponseWriter: w}
+		handler(recorder, r)
+		if recorder.status == http.StatusUnauthorized || recorder.status == http.StatusForbidden {
+			l.take(key, true)
+		}
+	}
+}
+
+// Limit decorates a RequestHandler to reject requests exceeding the rate
+// limit of their identity (the other keys are limited by LimitClients) or the
+// max number of concurrent downloads.
+func (l *RateLimit) Limit(handler RequestHandler) RequestHandler {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.config.Rate > 0 && l.config.Key == "identity" {
+			if ok, wait := l.take(l.key(r), true); !ok {
+				tooManyRequests(w, wait)
+				return
+			}
+		}
+
+		if l.streams != nil && r.Method == http.MethodGet {
+			select {
+			case l.streams <- struct{}{}:
+				defer func() { <-l.streams }()
+			default:
+				tooManyRequests(w, 1*time.Second)
+				return
+			}
+		}
+		handler(w, r)
+	}
+}
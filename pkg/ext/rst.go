@@ -0,0 +1,424 @@
+package ext
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// rstTarget matches the hyperlink targets (e.g. .. _name: url).
+	rstTarget = regexp.MustCompile(`^\.\.\s+_([^:]+):\s*(.*)$`)
+	// rstDirective matches the directives (e.g. .. code-block:: go).
+	rstDirective = regexp.MustCompile(`^\.\.\s+([\w:-]+)::\s*(.*)$`)
+	// rstOption matches the options of the directives (e.g. :alt: text).
+	rstOption = regexp.MustCompile(`^:([\w-]+):\s*(.*)$`)
+	// rstList matches the items of the bullet and enumerated lists.
+	rstList = regexp.MustCompile(`^(\s*)([-*+•]|#\.|\d+[.)]|\(\d+\))\s+(.*)$`)
+	// rstField matches the fields of the field lists (e.g. :Author: name).
+	rstField = regexp.MustCompile(`^:([^:]+):\s+(.*)$`)
+	// rstSimpleTable matches the borders of the simple tables.
+	rstSimpleTable = regexp.MustCompile(`^=+( +=+)+\s*$`)
+	// rstGridTable matches the borders of the grid tables.
+	rstGridTable = regexp.MustCompile(`^\+([-=]+\+)+\s*$`)
+	// rstInline matches the inline markup, i.e. inline literals, roles,
+	// hyperlinks and references.
+	rstInline = regexp.MustCompile("``(.+?)``" +
+		"|:([\\w:+.-]+):`([^`]+)`" +
+		"|`([^`<]*?)\\s*<([^>]+)>`__?" +
+		"|`([^`]+)`(__?)?" +
+		`|\b([\w.-]+)__?(?:\W|$)`)
+)
+
+// rstAdmonitions are the labels of the admonitions.
+var rstAdmonitions = map[string]string{
+	"note":      "Note",
+	"tip":       "Tip",
+	"hint":      "Hint",
+	"important": "Important",
+	"warning":   "Warning",
+	"caution":   "Caution",
+	"danger":    "Danger",
+	"attention": "Attention",
+	"error":     "Error",
+	"seealso":   "See also",
+}
+
+// rstCodeRoles are the roles rendered as inline code.
+var rstCodeRoles = map[string]bool{"code": true, "file": true, "command": true, "samp": true, "kbd": true, "program": true, "envvar": true, "math": true}
+
+// rstConverter converts the common subset of reStructuredText to markdown.
+type rstConverter struct {
+	// urls of the hyperlink targets by name
+	targets map[string]string
+	// adornments of the section titles by level
+	adornments []string
+}
+
+// rstToMarkdown converts a reStructuredText document to markdown.
+func rstToMarkdown(content []byte) ([]byte, error) {
+	lines := splitLines(content)
+	c := &rstConverter{targets: map[string]string{}}
+	// the chained targets without url share the url of the next target
+	var chained []string
+	for _, line := range lines {
+		match := rstTarget.FindStringSubmatch(strings.TrimSpace(line))
+		switch {
+		case match == nil:
+			chained = nil
+		case match[2] == "":
+			chained = append(chained, strings.ToLower(match[1]))
+		default:
+			for _, name := range append(chained, strings.ToLower(match[1])) {
+				c.targets[name] = match[2]
+			}
+			chained = nil
+		}
+	}
+	return []byte(strings.Join(c.convert(lines), "\n")), nil
+}
+
+// isAdornment checks whether a line is made of a repeated punctuation
+// character (e.g. =====).
+func isAdornment(line string) bool {
+	line = strings.TrimRight(line, " ")
+	if len(line) < 2 {
+		return false
+	}
+	for _, r := range line {
+		if r != rune(line[0]) || r > unicode.MaxASCII || !unicode.IsPunct(r) && !unicode.IsSymbol(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// indentation returns the number of leading spaces of a line.
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// indented returns the end of the block indented more than the indentation,
+// without its trailing blank lines.
+func indented(lines []string, start int, indent int) int {
+	end := start
+	for i := start; i < len(lines) && (strings.TrimSpace(lines[i]) == "" || indentation(lines[i]) > indent); i++ {
+		if strings.TrimSpace(lines[i]) != "" {
+			end = i + 1
+		}
+	}
+	return end
+}
+
+// level returns the level of the section titles with an adornment, given by
+// the order in which the adornments are first seen.
+func (c *rstConverter) level(adornment string) int {
+	for i, seen := range c.adornments {
+		if seen == adornment {
+			return i + 1
+		}
+	}
+	c.adornments = append(c.adornments, adornment)
+	if len(c.adornments) > 6 {
+		return 6
+	}
+	return len(c.adornments)
+}
+
+// reference returns the url of a reference to a hyperlink target.
+func (c *rstConverter) reference(name string) (string, bool) {
+	url, ok := c.targets[strings.ToLower(strings.Join(strings.Fields(name), " "))]
+	return url, ok
+}
+
+// inline converts the inline markup of a line to markdown.
+func (c *rstConverter) inline(line string) string {
+	return replaceInline(line, rstInline, func(groups []string) string {
+		switch {
+		case groups[1] != "":
+			return "`" + groups[1] + "`"
+		case groups[2] != "":
+			text := groups[3]
+			// e.g. :ref:`text <target>`
+			if i := strings.LastIndex(text, "<"); i > 0 && strings.HasSuffix(text, ">") {
+				text = strings.TrimSpace(text[:i])
+			}
+			if rstCodeRoles[groups[2]] {
+				return "`" + text + "`"
+			}
+			return escapeHTML(text)
+		case groups[5] != "":
+			url := groups[5]
+			if strings.HasSuffix(url, "_") {
+				url, _ = c.reference(strings.TrimSuffix(url, "_"))
+			}
+			if url == "" {
+				return escapeHTML(groups[4])
+			}
+			return link(escapeHTML(groups[4]), url)
+		case groups[6] != "":
+			if url, ok := c.reference(groups[6]); ok && groups[7] != "" {
+				return link(escapeHTML(groups[6]), url)
+			}
+			// interpreted text
+			return "*" + escapeHTML(groups[6]) + "*"
+		case groups[8] != "":
+			trailing := strings.TrimLeft(groups[0][len(groups[8]):], "_")
+			if url, ok := c.reference(groups[8]); ok {
+				return link(escapeHTML(groups[8]), url) + escapeHTML(trailing)
+			}
+		}
+		return escapeHTML(groups[0])
+	}, escapeHTML)
+}
+
+// directive converts a directive with its argument, options and content.
+func (c *rstConverter) directive(name string, argument string, options map[string]string, content []string) []string {
+	if label, ok := rstAdmonitions[name]; ok {
+		if argument != "" {
+			content = append([]string{argument}, content...)
+		}
+		return quote(label, c.convert(content))
+	}
+	switch name {
+	case "code", "code-block", "sourcecode":
+		return codeFence(argument, content)
+	case "math":
+		if argument != "" {
+			content = append([]string{argument}, content...)
+		}
+		return codeFence("math", content)
+	case "mermaid", "uml", "plantuml":
+		if name == "uml" {
+			name = "plantuml"
+		}
+		return codeFence(name, content)
+	case "image", "figure":
+		image := []string{"", "![" + escapeHTML(options["alt"]) + "](" + argument + ")"}
+		if target := options["target"]; target != "" {
+			image[1] = link(image[1], target)
+		}
+		return append(append(image, ""), c.convert(content)...)
+	case "raw":
+		if strings.TrimSpace(argument) == "html" {
+			return append(append([]string{""}, content...), "")
+		}
+	case "admonition", "topic", "sidebar", "rubric":
+		return quote(c.inline(argument), c.convert(content))
+	case "container", "only", "highlights", "compound":
+		return c.convert(content)
+	}
+	// e.g. contents (the template provides the table of contents) or toctree
+	return nil
+}
+
+// simpleTable converts a simple table, whose columns are given by its first
+// border. The rows with an empty first column continue the previous row, and
+// the first row is the header.
+func (c *rstConverter) simpleTable(lines []string) []string {
+	// starts of the columns
+	var columns []int
+	for i, r := range lines[0] {
+		if r == '=' && (i == 0 || lines[0][i-1] == ' ') {
+			columns = append(columns, i)
+		}
+	}
+	cell := func(line string, i int) string {
+		start, end := columns[i], len(line)
+		if i < len(columns)-1 && columns[i+1] < end {
+			end = columns[i+1]
+		}
+		if start >= len(line) {
+			return ""
+		}
+		return strings.TrimSpace(line[start:end])
+	}
+
+	var rows [][]string
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" || rstSimpleTable.MatchString(line) {
+			continue
+		}
+		row := make([]string, len(columns))
+		for i := range columns {
+			row[i] = c.inline(cell(line, i))
+		}
+		if row[0] == "" && len(rows) > 0 {
+			previous := rows[len(rows)-1]
+			for i := range row {
+				previous[i] = strings.TrimSpace(previous[i] + " " + row[i])
+			}
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return markdownTable(rows)
+}
+
+// gridTable converts a grid table, whose columns are given by the + of its
+// first border. The lines of a row are joined.
+func (c *rstConverter) gridTable(lines []string) []string {
+	var separators []int
+	for i, r := range lines[0] {
+		if r == '+' {
+			separators = append(separators, i)
+		}
+	}
+	var rows [][]string
+	var row []string
+	for _, line := range lines[1:] {
+		if rstGridTable.MatchString(line) {
+			if row != nil {
+				for i := range row {
+					row[i] = c.inline(strings.TrimSpace(row[i]))
+				}
+				rows = append(rows, row)
+				row = nil
+			}
+			continue
+		}
+		if row == nil {
+			row = make([]string, len(separators)-1)
+		}
+		for i := range row {
+			start, end := separators[i]+1, separators[i+1]
+			if start >= len(line) {
+				break
+			}
+			if end > len(line) {
+				end = len(line)
+			}
+			row[i] += " " + strings.TrimSpace(line[start:end])
+		}
+	}
+	return markdownTable(rows)
+}
+
+// convert converts the lines of a reStructuredText document (or block) to
+// markdown.
+func (c *rstConverter) convert(lines []string) []string {
+	var converted []string
+	// in a list, the indented lines continue the items instead of quoting
+	inList := false
+	blank := true
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " ")
+		trimmed := strings.TrimSpace(line)
+		next := ""
+		if i+1 < len(lines) {
+			next = strings.TrimRight(lines[i+1], " ")
+		}
+
+		if trimmed == "" {
+			converted = append(converted, "")
+			blank = true
+			continue
+		}
+		previousBlank := blank
+		blank = false
+		indent := indentation(line)
+
+		// explicit markup: targets, directives and comments
+		if strings.HasPrefix(trimmed, "..") && (trimmed == ".." || strings.HasPrefix(trimmed, ".. ")) {
+			end := indented(lines, i+1, indent)
+			if match := rstDirective.FindStringSubmatch(trimmed); match != nil {
+				block := dedent(lines[i+1 : end])
+				options := map[string]string{}
+				for len(block) > 0 && rstOption.MatchString(block[0]) {
+					option := rstOption.FindStringSubmatch(block[0])
+					options[option[1]] = option[2]
+					block = block[1:]
+				}
+				converted = append(converted, c.directive(strings.ToLower(match[1]), match[2], options, dedent(block))...)
+			}
+			i = end - 1
+			continue
+		}
+
+		switch {
+		case indent > 0 && !inList && previousBlank:
+			// block quotes
+			end := indented(lines, i, indent-1)
+			converted = append(converted, quote("", c.convert(dedent(lines[i:end])))...)
+			i = end - 1
+		case indent > 0:
+			converted = append(converted, c.inline(line))
+		case isAdornment(line) && i+2 < len(lines) && strings.TrimRight(lines[i+2], " ") == line && strings.TrimSpace(next) != "":
+			// section titles with an overline
+			level := c.level("over" + line[:1])
+			converted = append(converted, "", strings.Repeat("#", level)+" "+c.inline(strings.TrimSpace(next)), "")
+			i += 2
+		case isAdornment(next) && utf8.RuneCountInString(next) >= utf8.RuneCountInString(trimmed) && !isAdornment(line):
+			// section titles with an underline
+			level := c.level(next[:1])
+			converted = append(converted, "", strings.Repeat("#", level)+" "+c.inline(trimmed), "")
+			i++
+		case isAdornment(line) && len(line) >= 4 && previousBlank:
+			converted = append(converted, "---")
+		case rstSimpleTable.MatchString(line):
+			// the table ends with a border followed by a blank line
+			end := i + 1
+			for end < len(lines) && !(rstSimpleTable.MatchString(lines[end-1]) && end > i+1 && strings.TrimSpace(lines[end]) == "") {
+				end++
+			}
+			converted = append(converted, c.simpleTable(lines[i:end])...)
+			i = end - 1
+		case rstGridTable.MatchString(line):
+			end := i + 1
+			for end < len(lines) && (strings.HasPrefix(lines[end], "+") || strings.HasPrefix(lines[end], "|")) {
+				end++
+			}
+			converted = append(converted, c.gridTable(lines[i:end])...)
+			i = end - 1
+		case rstList.MatchString(line):
+			match := rstList.FindStringSubmatch(line)
+			marker := match[2]
+			switch {
+			case marker == "#.":
+				marker = "1."
+			case strings.HasSuffix(marker, ")"):
+				marker = strings.Trim(marker, "()") + "."
+			case marker == "•":
+				marker = "-"
+			}
+			converted = append(converted, match[1]+marker+" "+c.inline(match[3]))
+			inList = true
+			continue
+		case rstField.MatchString(line) && previousBlank:
+			match := rstField.FindStringSubmatch(line)
+			converted = append(converted, "**"+c.inline(match[1])+":** "+c.inline(match[2]))
+		case strings.HasSuffix(line, "::") && strings.TrimSpace(next) == "":
+			// literal blocks
+			text := strings.TrimSuffix(line, "::")
+			switch {
+			case strings.TrimSpace(text) == "":
+			case strings.HasSuffix(text, " "):
+				converted = append(converted, c.inline(strings.TrimSpace(text)))
+			default:
+				converted = append(converted, c.inline(text)+":")
+			}
+			start := i + 1
+			for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
+				start++
+			}
+			end := indented(lines, start, 0)
+			if start < len(lines) && indentation(lines[start]) > 0 {
+				converted = append(converted, codeFence("", dedent(lines[start:end]))...)
+				i = end - 1
+			}
+		case previousBlank && indentation(next) > 0 && strings.TrimSpace(next) != "":
+			// definition lists
+			end := indented(lines, i+1, 0)
+			converted = append(converted, "**"+c.inline(trimmed)+"**", "")
+			converted = append(converted, c.convert(dedent(lines[i+1:end]))...)
+			i = end - 1
+		default:
+			converted = append(converted, c.inline(line))
+		}
+		if indent == 0 {
+			inList = false
+		}
+	}
+	return converted
+}
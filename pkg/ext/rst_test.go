@@ -0,0 +1,90 @@
+package ext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRstToMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		rst      string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "sections",
+			rst:      "=====\nTitle\n=====\n\nSection\n=======\n\nSub *Section*\n-------------\n\nOther\n=====\n",
+			contains: []string{"\n# Title\n", "\n## Section\n", "\n### Sub *Section*\n", "\n## Other\n"},
+		},
+		{
+			name:     "simple table",
+			rst:      "=====  =====\nName   Size\n=====  =====\na.md   1\n       more\nb.md   2\n=====  =====\n\nafter\n",
+			contains: []string{"| Name | Size |\n| --- | --- |\n| a.md | 1 more |\n| b.md | 2 |", "\nafter"},
+		},
+		{
+			name:     "grid table",
+			rst:      "+------+------+\n| Name | Size |\n+======+======+\n| a.md | 1    |\n|      | more |\n+------+------+\n",
+			contains: []string{"| Name | Size |\n| --- | --- |\n| a.md | 1 more |"},
+		},
+		{
+			name:     "literal block",
+			rst:      "Example::\n\n  code *x*\n    more\n\nafter\n",
+			contains: []string{"Example:\n", "```\ncode *x*\n  more\n```", "\nafter"},
+		},
+		{
+			name:     "expanded literal block",
+			rst:      "Example ::\n\n  code\n\n::\n\n  other\n",
+			contains: []string{"Example\n", "```\ncode\n```", "```\nother\n```"},
+			excludes: []string{"::"},
+		},
+		{
+			name:     "code block",
+			rst:      ".. code-block:: go\n\n   func main() {}\n",
+			contains: []string{"```go\nfunc main() {}\n```"},
+		},
+		{
+			name:     "indented block quote",
+			rst:      "text\n\n  quoted *text*\n  more\n\nafter\n",
+			contains: []string{"text\n", "> quoted *text*\n> more", "\nafter"},
+		},
+		{
+			name:     "indented list continuation",
+			rst:      "- item\n  continued\n",
+			contains: []string{"- item\n  continued"},
+			excludes: []string{">"},
+		},
+		{
+			name:     "references",
+			rst:      "See `Go <https://go.dev>`_, `the docs`_ and docs_.\n\n.. _the docs:\n.. _docs: https://docs.example\n",
+			contains: []string{"[Go](https://go.dev),", "[the docs](https://docs.example)", "[docs](https://docs.example)."},
+		},
+		{
+			name:     "cross references",
+			rst:      "See :ref:`Install <install>`, :doc:`other` and :code:`main()`.",
+			contains: []string{"See Install, other and `main()`."},
+		},
+		{
+			name:     "admonition",
+			rst:      ".. note:: Read *this*.\n",
+			contains: []string{"> **Note**\n>\n> Read *this*."},
+		},
+	}
+	for _, test := range tests {
+		converted, err := rstToMarkdown([]byte(test.rst))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		for _, s := range test.contains {
+			if !strings.Contains(string(converted), s) {
+				t.Errorf("%s: expected %q in %q", test.name, s, converted)
+			}
+		}
+		for _, s := range test.excludes {
+			if strings.Contains(string(converted), s) {
+				t.Errorf("%s: unexpected %q in %q", test.name, s, converted)
+			}
+		}
+	}
+}
@@ -0,0 +1,448 @@
+package ext
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	glob "github.com/gobwas/glob"
+	minio "github.com/minio/minio-go"
+)
+
+// SearchConfig is used to config the search of objects inside the listed
+// folders, i.e. /_search?q=report&prefix=/docs/&mode=name|text
+type SearchConfig struct {
+	Enabled bool `json:"enabled"`
+	// Path of the search endpoint (default: /_search).
+	Path string `json:"path"`
+	// Max number of results (default: 100).
+	MaxResults int `json:"maxresults"`
+	// Enable the full-text search of the objects matching the globs.
+	FullText bool `json:"fulltext"`
+	// Comma separated list of globs of the objects indexed for the full-text
+	// search (default: **.md,**.txt).
+	FullTextObjects string `json:"fulltextobjects"`
+	// Objects larger than the size (bytes) are not indexed (default: 1 MB).
+	MaxIndexedSize int64 `json:"maxindexedsize"`
+	// Min number of seconds between the refreshes of the full-text index
+	// (default: 300). The index is refreshed in the background while the
+	// searches use the last index, and only the objects with a new ETag are
+	// retrieved again.
+	Refresh int `json:"refresh"`
+}
+
+// listingSearch provides the search endpoint of the listing extension.
+type listingSearch struct {
+	path            string
+	maxResults      int
+	fullText        bool
+	fullTextObjects []glob.Glob
+	maxIndexedSize  int64
+	refresh         time.Duration
+	// full-text index of each bucket
+	indexes map[string]*searchIndex
+	mutex   sync.Mutex
+}
+
+// searchIndex is the full-text index of the objects of a bucket.
+type searchIndex struct {
+	// last indexed docs, replaced but never modified by the refreshes
+	docs        map[string]indexedDoc
+	refreshedAt time.Time
+	refreshing  bool
+	// closed once the first refresh is done
+	ready chan struct{}
+	// error of the first refresh
+	err   error
+	mutex sync.Mutex
+}
+
+// indexedDoc is the content of an indexed object.
+type indexedDoc struct {
+	info    minio.ObjectInfo
+	content string
+	// lower case content to match case-insensitive
+	lower string
+}
+
+// snippetSize is the number of bytes around the match shown in the results.
+const snippetSize = 80
+
+// newListingSearch creates the search endpoint, or nil if disabled.
+func newListingSearch(config SearchConfig) (*listingSearch, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	search := &listingSearch{
+		path:           config.Path,
+		maxResults:     config.MaxResults,
+		fullText:       config.FullText,
+		maxIndexedSize: config.MaxIndexedSize,
+		refresh:        time.Duration(config.Refresh) * time.Second,
+		indexes:        map[string]*searchIndex{}}
+	if search.path == "" {
+		search.path = "/_search"
+	}
+	if search.maxResults <= 0 {
+		search.maxResults = 100
+	}
+	if search.maxIndexedSize <= 0 {
+		search.maxIndexedSize = 1024 * 1024
+	}
+	if search.refresh <= 0 {
+		search.refresh = 5 * time.Minute
+	}
+	fullTextObjects := config.FullTextObjects
+	if fullTextObjects == "" {
+		fullTextObjects = "**.md,**.txt"
+	}
+	globs, err := compileGlobs(fullTextObjects)
+	if err != nil {
+		return nil, err
+	}
+	search.fullTextObjects = globs
+	return search, nil
+}
+
+// index returns the full-text index of a bucket.
+func (s *listingSearch) index(bucketName string) *searchIndex {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	index, ok := s.indexes[bucketName]
+	if !ok {
+		index = &searchIndex{ready: make(chan struct{})}
+		s.indexes[bucketName] = index
+	}
+	return index
+}
+
+// nameMatcher returns a matcher of the object names (relative paths) for a
+// query. Queries with wildcards are globs (e.g. **.pdf), otherwise the names
+// containing the query (case-insensitive) are matched.
+func nameMatcher(query string) (matcher, error) {
+	if strings.ContainsAny(query, "*?[{") {
+		g, err := glob.Compile(query, '/')
+		if err != nil {
+			return nil, err
+		}
+		return func(name string) bool {
+			return g.Match(name) || g.Match(path.Base(name))
+		}, nil
+	}
+	query = strings.ToLower(query)
+	return func(name string) bool {
+		return strings.Contains(strings.ToLower(name), query)
+	}, nil
+}
+
+// searchNames walks the objects inside a folder url, and returns the allowed
+// objects with a name matching the query.
+func (ext *ListFolderExt) searchNames(url string, query string, allowed func(url string) bool) (listingPage, error) {
+	bucketName, prefix := ext.helper.GetBucketNameAndPrefix(url)
+	page := listingPage{BucketName: bucketName, URL: url}
+	if bucketName == "" {
+		return page, errors.New("Bucket name not known")
+	}
+	prefix = ext.helper.Prefix + prefix
+	page.Prefix = prefix
+
+	match, err := nameMatcher(query)
+	if err != nil {
+		return page, err
+	}
+
+	walked := 0
+	token := ""
+	for {
+		isRecursive := true
+		result, err := ext.helper.ListObjectsPage(bucketName, prefix, token, isRecursive, maxListingPageSize)
+		if err != nil {
+			return page, err
+		}
+		for _, info := range result.Contents {
+			if walked >= ext.maxObjects || len(page.Items) >= ext.search.maxResults {
+				page.Truncated = true
+				break
+			}
+			walked++
+
+			name := strings.TrimPrefix(info.Key, prefix)
+			if name == "" || strings.HasSuffix(name, "/") || !match(name) || !ext.isListedObject(url, name) || !allowed(url+name) {
+				continue
+			}
+			item := ext.newListingItem(name, url, info)
+			page.Items = append(page.Items, item)
+		}
+		if page.Truncated || !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	return page, nil
+}
+
+// snapshot returns the last full-text index of a bucket, and starts a
+// refresh in the background if it is older than the refresh interval. Only
+// the first search of a bucket waits for the index to be built.
+func (ext *ListFolderExt) snapshot(bucketName string) (map[string]indexedDoc, error) {
+	index := ext.search.index(bucketName)
+	index.mutex.Lock()
+	if !index.refreshing && time.Since(index.refreshedAt) >= ext.search.refresh {
+		index.refreshing = true
+		go ext.refresh(index, bucketName, index.docs)
+	}
+	docs, ready := index.docs, index.ready
+	index.mutex.Unlock()
+	if docs != nil {
+		return docs, nil
+	}
+
+	<-ready
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	return index.docs, index.err
+}
+
+// refresh replaces the full-text index of a bucket. Only new objects or
+// objects with a new ETag are retrieved, the others are copied from the
+// previous docs.
+func (ext *ListFolderExt) refresh(index *searchIndex, bucketName string, previous map[string]indexedDoc) {
+	docs, err := ext.indexObjects(bucketName, previous)
+
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	index.refreshing = false
+	if err != nil {
+		ext.core.Sugar.Warnf("Search[%s]: failed to refresh the index: %s", bucketName, err)
+		if index.docs == nil {
+			// wake up the first searches, and retry with the next one
+			index.err = err
+			close(index.ready)
+			index.ready = make(chan struct{})
+			return
+		}
+		// keep the last index until the next refresh
+		index.refreshedAt = time.Now()
+		return
+	}
+	first := index.docs == nil
+	index.docs, index.err, index.refreshedAt = docs, nil, time.Now()
+	if first {
+		close(index.ready)
+	}
+}
+
+// indexObjects returns the content of the objects of a bucket matching the
+// full-text globs.
+func (ext *ListFolderExt) indexObjects(bucketName string, previous map[string]indexedDoc) (map[string]indexedDoc, error) {
+	docs := map[string]indexedDoc{}
+	walked := 0
+	token := ""
+	for walked < ext.maxObjects {
+		isRecursive := true
+		result, err := ext.helper.ListObjectsPage(bucketName, ext.helper.Prefix, token, isRecursive, maxListingPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range result.Contents {
+			walked++
+			if info.Size > ext.search.maxIndexedSize || !matchAny(ext.search.fullTextObjects, info.Key) {
+				continue
+			}
+			doc, ok := previous[info.Key]
+			if ok && doc.info.ETag == info.ETag {
+				docs[info.Key] = doc
+				continue
+			}
+			obj, err := ext.helper.Client.GetObject(bucketName, info.Key, minio.GetObjectOptions{})
+			if err != nil {
+				if ok {
+					docs[info.Key] = doc
+				}
+				continue
+			}
+			content, err := ioutil.ReadAll(obj)
+			obj.Close()
+			if err != nil {
+				if ok {
+					docs[info.Key] = doc
+				}
+				continue
+			}
+			// drafts are indexed without content so that they never match
+			if isDraft(content) {
+				docs[info.Key] = indexedDoc{info: info}
+				continue
+			}
+			docs[info.Key] = indexedDoc{
+				info:    info,
+				content: string(content),
+				lower:   strings.ToLower(string(content))}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	return docs, nil
+}
+
+// snippet returns the text around the first match of a term.
+func (doc indexedDoc) snippet(term string) string {
+	text := doc.content
+	// offsets of the lower case content only match if the lengths are equal
+	if len(doc.lower) != len(doc.content) {
+		text = doc.lower
+	}
+	i := strings.Index(doc.lower, term)
+	start, end := i-snippetSize/2, i+len(term)+snippetSize/2
+	if start < 0 {
+		start = 0
+	}
+	if end > len(text) {
+		end = len(text)
+	}
+	return strings.Join(strings.Fields(strings.ToValidUTF8(text[start:end], "")), " ")
+}
+
+// searchText returns the allowed indexed objects inside a folder url
+// containing all the terms of the query.
+func (ext *ListFolderExt) searchText(url string, query string, allowed func(url string) bool) (listingPage, error) {
+	bucketName, prefix := ext.helper.GetBucketNameAndPrefix(url)
+	page := listingPage{BucketName: bucketName, URL: url}
+	if bucketName == "" {
+		return page, errors.New("Bucket name not known")
+	}
+	prefix = ext.helper.Prefix + prefix
+	page.Prefix = prefix
+
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return page, nil
+	}
+
+	docs, err := ext.snapshot(bucketName)
+	if err != nil {
+		return page, err
+	}
+
+	for key, doc := range docs {
+		name := strings.TrimPrefix(key, prefix)
+		if !strings.HasPrefix(key, prefix) || name == "" || !ext.isListedObject(url, name) {
+			continue
+		}
+		matched := true
+		for _, term := range terms {
+			if !strings.Contains(doc.lower, term) {
+				matched = false
+				break
+			}
+		}
+		if !matched || !allowed(url+name) {
+			continue
+		}
+		if len(page.Items) >= ext.search.maxResults {
+			page.Truncated = true
+			break
+		}
+		item := ext.newListingItem(name, url, doc.info)
+		item.Snippet = doc.snippet(terms[0])
+		page.Items = append(page.Items, item)
+	}
+	return page, nil
+}
+
+// searchURL returns the url of the search results with the options.
+func (ext *ListFolderExt) searchURL(folder string, query string, mode string, options listingOptions) string {
+	values := url.Values{"q": {query}, "prefix": {folder}}
+	if mode != "name" {
+		values.Set("mode", mode)
+	}
+	if options.Sort != "name" {
+		values.Set("sort", options.Sort)
+	}
+	if options.Desc {
+		values.Set("order", "desc")
+	}
+	return ext.link(ext.search.path) + "?" + values.Encode()
+}
+
+// HandleSearch decorates a RequestHandler to return the objects inside a
+// folder (?prefix=/docs/) matching a query (?q=...) by name, or by content
+// if the full-text search is enabled (?mode=text). The folder and the objects
+// found are checked by the access decorators (e.g. authorization) as if they
+// were requested.
+func (ext *ListFolderExt) HandleSearch(handler RequestHandler) RequestHandler {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != ext.search.path || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			handler(w, r)
+			return
+		}
+		query := r.URL.Query()
+		options, err := ext.parseListingOptions(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		folder := normalizeFolderURL(query.Get("prefix"))
+		q := strings.TrimSpace(query.Get("q"))
+		mode := query.Get("mode")
+		if !ext.core.AuthorizeURL(w, r, folder) {
+			return
+		}
+		allowed := ext.allowedURLs(r)
+
+		var page listingPage
+		switch {
+		case mode == "" || mode == "name":
+			mode = "name"
+			if q != "" {
+				page, err = ext.searchNames(folder, q, allowed)
+			}
+		case mode == "text" && ext.search.fullText:
+			page, err = ext.searchText(folder, q, allowed)
+		default:
+			http.Error(w, fmt.Sprintf("invalid mode: %s", mode), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sortItems(page.Items, options.Sort, options.Desc)
+
+		if wantsJSON(r) {
+			page.Query = q
+			serveJSON(w, r, page)
+			return
+		}
+
+		options.Recursive, options.Flat = true, true
+		data := ext.listingData(folder, options, page)
+		data.Query = q
+		data.SearchMode = mode
+		data.SortByName = ext.searchURL(folder, q, mode, listingOptions{Sort: "name", Desc: options.Sort == "name" && !options.Desc})
+		data.SortBySize = ext.searchURL(folder, q, mode, listingOptions{Sort: "size", Desc: options.Sort == "size" && !options.Desc})
+		data.SortByDate = ext.searchURL(folder, q, mode, listingOptions{Sort: "date", Desc: options.Sort == "date" && !options.Desc})
+		res, err := ext.render(data, fmt.Sprintf("Search[%s/%s] %q: %d result(s)", page.BucketName, page.Prefix, q, len(page.Items)))
+		if res.Msg != "" {
+			ext.core.Sugar.Info(res.Msg)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if r.Method == http.MethodHead {
+			ext.core.SetHeaders(w, res.Info)
+			return
+		}
+		ext.core.ServeResource(w, r, res)
+	}
+}
@@ -0,0 +1,93 @@
+package ext
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/e2fyi/minio-web/pkg/core"
+)
+
+func TestSearchChecksAccess(t *testing.T) {
+	server, helper := newFakeBucket(t, map[string]string{"docs/report.md": "", "internal/report.md": ""})
+	defer server.Close()
+
+	c := core.NewCore()
+	c.ChainStatObject(helper.StatObject)
+	c.ChainGetObject(helper.GetObject)
+	c.ApplyExtension(ListFolderExtension(helper, true, "*", ListingConfig{Search: SearchConfig{Enabled: true}}, ""))
+	c.ApplyExtension(AuthorizationExtension([]AuthPolicy{{Paths: "/internal/**", Groups: "admin"}}))
+	c.Init()
+	handler := c.Handler()
+
+	tests := []struct {
+		url      string
+		status   int
+		contains []string
+		excludes []string
+	}{
+		{url: "/_search?q=report&prefix=/internal/", status: http.StatusUnauthorized, excludes: []string{"report.md"}},
+		{url: "/_search?q=report&prefix=/", status: http.StatusOK, contains: []string{"/docs/report.md"}, excludes: []string{"/internal/report.md"}},
+		{url: "/_search?q=report&prefix=/docs/", status: http.StatusOK, contains: []string{"/docs/report.md"}},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, test.url, nil))
+		if w.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d", test.url, test.status, w.Code)
+		}
+		body := w.Body.String()
+		for _, s := range test.contains {
+			if !strings.Contains(body, s) {
+				t.Errorf("%s: expected %q in the results", test.url, s)
+			}
+		}
+		for _, s := range test.excludes {
+			if strings.Contains(body, s) {
+				t.Errorf("%s: unexpected %q in the results", test.url, s)
+			}
+		}
+	}
+}
+
+func TestSearchIndexRefreshesInBackground(t *testing.T) {
+	objects := map[string]string{"docs/a.md": "old", "docs/b.txt": "text"}
+	server, helper := newFakeBucket(t, objects)
+	defer server.Close()
+
+	ext, err := NewListFolderExt(helper, true, "*", ListingConfig{Search: SearchConfig{Enabled: true, FullText: true}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := core.NewCore()
+	ext.core = &c
+	ext.search.refresh = time.Nanosecond
+
+	// the first search waits for the index
+	docs, err := ext.snapshot("bucket")
+	if err != nil || docs["docs/a.md"].content != "old" || docs["docs/b.txt"].content != "text" {
+		t.Fatalf("expected the objects to be indexed, got %v %v", docs, err)
+	}
+
+	// the next searches use the last index while it is refreshed
+	objects["docs/a.md"] = "new content"
+	docs, err = ext.snapshot("bucket")
+	if err != nil || docs["docs/a.md"].content != "old" {
+		t.Errorf("expected the last index, got %v %v", docs, err)
+	}
+
+	index := ext.search.index("bucket")
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		index.mutex.Lock()
+		refreshing, docs := index.refreshing, index.docs
+		index.mutex.Unlock()
+		if !refreshing && docs["docs/a.md"].content == "new content" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the index to be refreshed, got %v", docs)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package ext
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// shareProvider is the provider of the identity of a signed url request.
+const shareProvider = "share"
+
+// ShareConfig is used to config the signed share urls extension.
+type ShareConfig struct {
+	// Comma separated list of active keys (e.g. k2:secret2,k1:secret1).
+	// Keys can be rotated by adding a new key while keeping the old ones
+	// until the urls signed with them expire.
+	Keys string `json:"keys"`
+	// Id of the key to sign new urls with (default: first key).
+	SigningKey string `json:"signingkey"`
+	// Comma separated list of url prefixes which can only be accessed with
+	// a signed url.
+	Protected string `json:"protected"`
+}
+
+// ShareLinks provides the decorator to verify time-limited signed urls.
+type ShareLinks struct {
+	keys       map[string][]byte
+	signingKey string
+	protected  []string
+}
+
+// ShareLinksExtension installs the extension to verify signed share urls
+// before the resource is retrieved. Must be applied after the authentication
+// extensions so that valid signed urls bypass authentication.
+func ShareLinksExtension(config ShareConfig) Extension {
+	return func(c *Core) (string, error) {
+		if config.Keys == "" {
+			return "share urls: disabled", nil
+		}
+		share, err := NewShareLinks(config)
+		if err != nil {
+			return "share urls: errored", err
+		}
+		c.ApplyAccess(share.VerifySignedURL)
+		return fmt.Sprintf("share urls: %d key(s)", len(share.keys)), nil
+	}
+}
+
+// NewShareLinks creates a new ShareLinks object.
+func NewShareLinks(config ShareConfig) (*ShareLinks, error) {
+	share := &ShareLinks{
+		keys:       map[string][]byte{},
+		signingKey: config.SigningKey,
+		protected:  splitList(config.Protected)}
+
+	for _, key := range splitList(config.Keys) {
+		parts := strings.SplitN(key, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("share urls: invalid key %q (expected id:secret)", parts[0])
+		}
+		share.keys[parts[0]] = []byte(parts[1])
+		if share.signingKey == "" {
+			share.signingKey = parts[0]
+		}
+	}
+	if _, ok := share.keys[share.signingKey]; !ok {
+		return nil, fmt.Errorf("share urls: unknown signing key %q", share.signingKey)
+	}
+	return share, nil
+}
+
+// signature computes the signature of a scope (object key or prefix) with a
+// key.
+func (s *ShareLinks) signature(kid string, scope string, expires int64) ([]byte, bool) {
+	secret, ok := s.keys[kid]
+	if !ok {
+		return nil, false
+	}
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s\n%s\n%d", kid, scope, expires)
+	return mac.Sum(nil), true
+}
+
+// Sign returns the signed url which grants access to the path (or to any url
+// under the path if prefix is set) until expires.
+func (s *ShareLinks) Sign(path string, expires time.Time, prefix bool) string {
+	query := url.Values{}
+	if prefix {
+		query.Set("scope", path)
+	}
+	sig, _ := s.signature(s.signingKey, path, expires.Unix())
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("kid", s.signingKey)
+	query.Set("sig", base64.RawURLEncoding.EncodeToString(sig))
+	return (&url.URL{Path: path, RawQuery: query.Encode()}).String()
+}
+
+// verify checks the signature and expiry of a signed url.
+func (s *ShareLinks) verify(u *url.URL) error {
+	query := u.Query()
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return errors.New("invalid expiry")
+	}
+	if time.Now().Unix() > expires {
+		return errors.New("url expired")
+	}
+
+	scope := u.Path
+	if query.Get("scope") != "" {
+		scope = query.Get("scope")
+		// the scope only covers the urls inside the folder (e.g. /a/ and
+		// not /ab)
+		if u.Path != scope && !strings.HasPrefix(u.Path, strings.TrimSuffix(scope, "/")+"/") {
+			return errors.New("url outside of scope")
+		}
+	}
+	expected, ok := s.signature(query.Get("kid"), scope, expires)
+	if !ok {
+		return errors.New("unknown key")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(query.Get("sig"))
+	if err != nil || !hmac.Equal(sig, expected) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// VerifySignedURL decorates a RequestHandler to verify signed urls. Requests
+// with a valid signature are treated as authenticated, while requests to
+// protected prefixes without one are rejected.
+func (s *ShareLinks) VerifySignedURL(handler RequestHandler) RequestHandler {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sig") == "" {
+			if hasAnyPrefix(r.URL.Path, s.protected) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			handler(w, r)
+			return
+		}
+
+		if err := s.verify(r.URL); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		handler(w, WithIdentity(r, Identity{Name: r.URL.Query().Get("kid"), Provider: shareProvider}))
+	}
+}
@@ -0,0 +1,238 @@
+package ext
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/lexers"
+	humanize "github.com/dustin/go-humanize"
+	glob "github.com/gobwas/glob"
+)
+
+// sourceTemplate is the default template of the source viewer.
+const sourceTemplate = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8" />
+  <meta name="viewport" content="width=device-width, initial-scale=1" />
+  <title>{{.Name}}</title>
+  <style>
+    body {
+      color: #24292e;
+      font-family: -apple-system, BlinkMacSystemFont, Segoe UI, Helvetica, Arial,
+        sans-serif;
+      margin: 0 auto;
+      max-width: 1280px;
+      padding: 45px;
+    }
+    a {
+      color: #0366d6;
+      text-decoration: none;
+    }
+    header {
+      align-items: baseline;
+      border-bottom: 1px solid #eaecef;
+      display: flex;
+      justify-content: space-between;
+      margin-bottom: 16px;
+    }
+    pre {
+      font-family: SFMono-Regular, Consolas, Liberation Mono, Menlo, monospace;
+      font-size: 12px;
+      line-height: 20px;
+      overflow: auto;
+      padding: 16px;
+    }
+    pre [id^="L"]:target {
+      background-color: #fffbdd;
+    }
+  </style>
+</head>
+<body>
+  <header>
+    <h2>{{.Name}}</h2>
+    <span>{{.Lines}} lines · {{.HumanSize}} · <a href="{{.RawURL}}">Raw</a></span>
+  </header>
+  {{.Content}}
+</body>
+</html>
+`
+
+// SourceConfig is used to config the source viewer, which renders code
+// objects as highlighted HTML with linkable line numbers (e.g. /main.go#L10).
+// The original object is returned with ?raw=1.
+type SourceConfig struct {
+	// Comma separated list of globs of the object paths rendered (e.g.
+	// **.{go,py,yaml}). Disabled if empty.
+	Objects string `json:"objects"`
+	// Name of the chroma style (default: github).
+	Style string `json:"style"`
+	// Objects larger than the size (bytes) are returned as is (default: 1 MB).
+	MaxSize int64 `json:"maxsize"`
+	// Path to a html/template to render the source (default: built-in).
+	Template string `json:"template"`
+}
+
+// SourceData provides the view to the HTML template of the source viewer.
+type SourceData struct {
+	// name of the object (e.g. main.go)
+	Name string
+	Key  string
+	// url of the original object
+	RawURL  string
+	Size    int64
+	Lines   int
+	Content template.HTML
+}
+
+// HumanSize returns the size of the source in a human readable format.
+func (data SourceData) HumanSize() string {
+	return humanize.Bytes(uint64(data.Size))
+}
+
+// SourceViewer provides the decorator to render code objects as HTML.
+type SourceViewer struct {
+	objects     []glob.Glob
+	highlighter highlighter
+	maxSize     int64
+	template    *template.Template
+	core        *Core
+}
+
+// SourceViewerExtension installs the source viewer if globs of the objects
+// are provided.
+func SourceViewerExtension(config SourceConfig) Extension {
+	return func(c *Core) (string, error) {
+		if config.Objects == "" {
+			return "source viewer: disabled", nil
+		}
+		viewer, err := NewSourceViewer(config)
+		if err != nil {
+			return "source viewer: errored", err
+		}
+		viewer.core = c
+		c.ApplyRequest(viewer.HandleSource)
+		return fmt.Sprintf("source viewer: %s", config.Objects), nil
+	}
+}
+
+// NewSourceViewer creates a new SourceViewer object.
+func NewSourceViewer(config SourceConfig) (*SourceViewer, error) {
+	objects, err := compileGlobs(config.Objects)
+	if err != nil {
+		return nil, err
+	}
+	var sourceViewerTemplate *template.Template
+	if config.Template != "" {
+		sourceViewerTemplate, err = template.ParseFiles(config.Template)
+	} else {
+		sourceViewerTemplate, err = template.New("source").Parse(sourceTemplate)
+	}
+	if err != nil {
+		return nil, err
+	}
+	viewer := &SourceViewer{
+		objects:     objects,
+		highlighter: newHighlighter(config.Style),
+		maxSize:     config.MaxSize,
+		template:    sourceViewerTemplate}
+	if viewer.maxSize <= 0 {
+		viewer.maxSize = 1024 * 1024
+	}
+	return viewer, nil
+}
+
+// isRaw checks whether the original object is requested (?raw=1).
+func isRaw(r *http.Request) bool {
+	raw, _ := strconv.ParseBool(r.URL.Query().Get("raw"))
+	return raw
+}
+
+// render retrieves a code object with the GetObject handler and renders it as
+// a HTML Resource. False is returned if the object is missing, too large, not
+// a text or a document (rendered by the markdown extension, e.g. drafts).
+func (s *SourceViewer) render(url string) (Resource, bool, error) {
+	res, err := s.core.GetObject(url)
+	if closer, ok := res.Data.(io.Closer); ok {
+		defer closer.Close()
+	}
+	if err != nil || res.Data == nil || res.Info.Size > s.maxSize || isDocument(res) {
+		return Resource{}, false, nil
+	}
+	content, err := ioutil.ReadAll(res.Data)
+	if err != nil || !utf8.Valid(content) {
+		return Resource{}, false, nil
+	}
+
+	name := path.Base(url)
+	lexer := lexers.Match(name)
+	if lexer == nil {
+		lexer = lexers.Analyse(string(content))
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	highlighted, err := s.highlighter.highlight(string(content), lexer, html.WithLineNumbers(true), html.LinkableLineNumbers(true, "L"))
+	if err != nil {
+		return Resource{}, false, err
+	}
+
+	data := SourceData{
+		Name:    name,
+		Key:     res.Info.Key,
+		RawURL:  "?raw=1",
+		Size:    res.Info.Size,
+		Lines:   bytes.Count(content, []byte("\n")),
+		Content: template.HTML(highlighted)}
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		data.Lines++
+	}
+	var rendered bytes.Buffer
+	if err := s.template.Execute(&rendered, data); err != nil {
+		return Resource{}, false, err
+	}
+	return Resource{
+		Msg:  fmt.Sprintf("Source[%s/%s] %s", res.Info.Bucket, res.Info.Key, lexer.Config().Name),
+		Data: bytes.NewReader(rendered.Bytes()),
+		Info: ResourceInfo{
+			Size:         int64(rendered.Len()),
+			ContentType:  "text/html; charset=utf-8",
+			LastModified: res.Info.LastModified}}, true, nil
+}
+
+// HandleSource decorates a RequestHandler to render the code objects matching
+// the globs as highlighted HTML, unless ?raw=1 is requested.
+func (s *SourceViewer) HandleSource(handler RequestHandler) RequestHandler {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		url := r.URL.Path
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) || isRaw(r) ||
+			strings.HasSuffix(url, "/") || !matchAny(s.objects, strings.TrimPrefix(url, "/")) {
+			handler(w, r)
+			return
+		}
+		res, ok, err := s.render(url)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			handler(w, r)
+			return
+		}
+		if r.Method == http.MethodHead {
+			s.core.SetHeaders(w, res.Info)
+			return
+		}
+		s.core.ServeResource(w, r, res)
+	}
+}
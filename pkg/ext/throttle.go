@@ -0,0 +1,233 @@
+package ext
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	glob "github.com/gobwas/glob"
+)
+
+// throttleChunkSize is the max number of bytes written at once by a throttled
+// response.
+const throttleChunkSize = 32 * 1024
+
+// ThrottleConfig is used to config the bandwidth throttling extension.
+type ThrottleConfig struct {
+	// Max bytes per second shared by all responses (0 for unlimited).
+	Global int `json:"global"`
+	// Max bytes per second for each response (0 for unlimited).
+	PerConnection int `json:"perconnection"`
+	// Limits for specific urls or content types (the first matching rule
+	// applies instead of the default per connection limit).
+	Rules []ThrottleRule `json:"rules"`
+}
+
+// ThrottleRule describes the bandwidth limits for the objects with an url
+// matching the globs or with a matching content type.
+type ThrottleRule struct {
+	// Comma separated list of url globs (e.g. /datasets/**).
+	Paths string `json:"paths"`
+	// Comma separated list of globs for content types (e.g. video/*).
+	ContentTypes string `json:"contenttypes"`
+	// Max bytes per second shared by all responses matching the rule.
+	Global int `json:"global"`
+	// Max bytes per second for each response matching the rule.
+	PerConnection int `json:"perconnection"`
+}
+
+// Throttle provides the decorator to limit the bandwidth of the responses.
+type Throttle struct {
+	global        *byteLimiter
+	perConnection int
+	rules         []throttleRule
+	// helper to infer the url of the objects
+	helper *MinioHelper
+}
+
+// throttleRule is a compiled ThrottleRule.
+type throttleRule struct {
+	paths         []glob.Glob
+	contentTypes  []glob.Glob
+	global        *byteLimiter
+	perConnection int
+}
+
+// byteLimiter is a token bucket of bytes.
+type byteLimiter struct {
+	rate      float64
+	tokens    float64
+	updatedAt time.Time
+	mutex     sync.Mutex
+}
+
+// throttledWriter is a http.ResponseWriter which waits on the limiters before
+// writing.
+type throttledWriter struct {
+	http.ResponseWriter
+	limiters []*byteLimiter
+}
+
+// ThrottleExtension installs the extension to limit the bandwidth of the
+// responses. Must be applied after the other Serve decorators so that all
+// the written bytes are throttled.
+func ThrottleExtension(helper *MinioHelper, config ThrottleConfig) Extension {
+	return func(c *Core) (string, error) {
+		if config.Global <= 0 && config.PerConnection <= 0 && len(config.Rules) == 0 {
+			return "throttle: disabled", nil
+		}
+		throttle, err := NewThrottle(helper, config)
+		if err != nil {
+			return "throttle: errored", err
+		}
+		c.ApplyServe(throttle.ThrottleServe)
+		return fmt.Sprintf("throttle: %d B/s global, %d B/s per connection, %d rule(s)",
+			config.Global, config.PerConnection, len(config.Rules)), nil
+	}
+}
+
+// NewThrottle creates a new Throttle object.
+func NewThrottle(helper *MinioHelper, config ThrottleConfig) (*Throttle, error) {
+	throttle := &Throttle{
+		global:        newByteLimiter(config.Global),
+		perConnection: config.PerConnection,
+		helper:        helper}
+
+	for _, rule := range config.Rules {
+		compiled := throttleRule{
+			global:        newByteLimiter(rule.Global),
+			perConnection: rule.PerConnection}
+		for _, pattern := range splitList(rule.Paths) {
+			g, err := glob.Compile(pattern, '/')
+			if err != nil {
+				return nil, err
+			}
+			compiled.paths = append(compiled.paths, g)
+		}
+		for _, pattern := range splitList(rule.ContentTypes) {
+			g, err := glob.Compile(pattern)
+			if err != nil {
+				return nil, err
+			}
+			compiled.contentTypes = append(compiled.contentTypes, g)
+		}
+		throttle.rules = append(throttle.rules, compiled)
+	}
+	return throttle, nil
+}
+
+// newByteLimiter creates a limiter for a rate (bytes per second), or nil if
+// unlimited.
+func newByteLimiter(rate int) *byteLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &byteLimiter{rate: float64(rate), tokens: float64(rate), updatedAt: time.Now()}
+}
+
+// wait blocks until n bytes can be sent.
+func (l *byteLimiter) wait(n int) {
+	l.mutex.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.updatedAt).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.updatedAt = now
+	// tokens can go negative to reserve bytes for the caller
+	l.tokens -= float64(n)
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mutex.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// Unwrap returns the throttled http.ResponseWriter.
+func (w *throttledWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Write writes the data in chunks once allowed by the limiters.
+func (w *throttledWriter) Write(data []byte) (int, error) {
+	written := 0
+	for len(data) > 0 {
+		n := len(data)
+		if n > throttleChunkSize {
+			n = throttleChunkSize
+		}
+		for _, limiter := range w.limiters {
+			limiter.wait(n)
+		}
+		m, err := w.ResponseWriter.Write(data[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		data = data[n:]
+	}
+	return written, nil
+}
+
+// matches checks whether the rule applies to the resource at the url (empty
+// if unknown, e.g. rendered listings).
+func (rule throttleRule) matches(url string, info ResourceInfo) bool {
+	for _, g := range rule.paths {
+		if url != "" && g.Match(url) {
+			return true
+		}
+	}
+	for _, g := range rule.contentTypes {
+		if g.Match(info.ContentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// url returns the url of a resource (e.g. with the prefix of the objects
+// removed), or an empty string if unknown.
+func (t *Throttle) url(info ResourceInfo) string {
+	if info.Key == "" || t.helper == nil {
+		return ""
+	}
+	return t.helper.GetURL(info.Bucket, info.Key)
+}
+
+// limiters returns the limiters applicable to a resource.
+func (t *Throttle) limiters(info ResourceInfo) []*byteLimiter {
+	var limiters []*byteLimiter
+	perConnection := t.perConnection
+	url := t.url(info)
+	for _, rule := range t.rules {
+		if rule.matches(url, info) {
+			perConnection = rule.perConnection
+			if rule.global != nil {
+				limiters = append(limiters, rule.global)
+			}
+			break
+		}
+	}
+	if t.global != nil {
+		limiters = append(limiters, t.global)
+	}
+	if limiter := newByteLimiter(perConnection); limiter != nil {
+		limiters = append(limiters, limiter)
+	}
+	return limiters
+}
+
+// ThrottleServe decorates a Serve function to limit the bandwidth used to
+// write the response.
+func (t *Throttle) ThrottleServe(Serve ServeHandler) ServeHandler {
+
+	return func(w http.ResponseWriter, resource Resource) error {
+		limiters := t.limiters(resource.Info)
+		if len(limiters) == 0 {
+			return Serve(w, resource)
+		}
+		return Serve(&throttledWriter{ResponseWriter: w, limiters: limiters}, resource)
+	}
+}
@@ -0,0 +1,38 @@
+package ext
+
+import "testing"
+
+func TestThrottleRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		helper *MinioHelper
+		info   ResourceInfo
+		rule   int
+	}{
+		{"url", &MinioHelper{BucketName: "bucket"}, ResourceInfo{Bucket: "bucket", Key: "datasets/a.csv"}, 0},
+		{"url with object prefix", &MinioHelper{BucketName: "bucket", Prefix: "web/"}, ResourceInfo{Bucket: "bucket", Key: "web/datasets/a.csv"}, 0},
+		{"url with bucket", &MinioHelper{}, ResourceInfo{Bucket: "datasets", Key: "a.csv"}, 0},
+		{"other url", &MinioHelper{BucketName: "bucket"}, ResourceInfo{Bucket: "bucket", Key: "docs/datasets/a.csv"}, -1},
+		{"content type", &MinioHelper{BucketName: "bucket"}, ResourceInfo{Bucket: "bucket", Key: "a.mp4", ContentType: "video/mp4"}, 1},
+		{"rendered resource", &MinioHelper{BucketName: "bucket"}, ResourceInfo{ContentType: "text/html"}, -1},
+	}
+	for _, test := range tests {
+		throttle, err := NewThrottle(test.helper, ThrottleConfig{Rules: []ThrottleRule{
+			{Paths: "/datasets/**", Global: 100},
+			{ContentTypes: "video/*", Global: 200}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rule := -1
+		url := throttle.url(test.info)
+		for i := range throttle.rules {
+			if throttle.rules[i].matches(url, test.info) {
+				rule = i
+				break
+			}
+		}
+		if rule != test.rule {
+			t.Errorf("%s: expected rule %d, got %d (url %q)", test.name, test.rule, rule, url)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package ext
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"strings"
+	"unicode"
+
+	"gitlab.com/golang-commonmark/markdown"
+)
+
+// anchorIcon is the octicon link shown on hover by the markdown template.
+const anchorIcon = `<svg class="octicon octicon-link" viewBox="0 0 16 16" version="1.1" width="16" height="16" aria-hidden="true"><path fill-rule="evenodd" d="M4 9h1v1H4c-1.5 0-3-1.69-3-3.5S2.55 3 4 3h4c1.45 0 3 1.69 3 3.5 0 1.41-.91 2.72-2 3.25V8.59c.58-.45 1-1.27 1-2.09C10 5.22 8.98 4 8 4H4c-.98 0-2 1.22-2 2.5S3 9 4 9zm9-3h-1v1h1c1 0 2 1.22 2 2.5S13.98 12 13 12H9c-.98 0-2-1.22-2-2.5 0-.83.42-1.64 1-2.09V6.25c-1.09.53-2 1.84-2 3.25C6 11.31 7.55 13 9 13h4c1.45 0 3-1.69 3-3.5S14.5 6 13 6z"></path></svg>`
+
+// TOCEntry is a heading of a table of contents, with its subheadings.
+type TOCEntry struct {
+	Title string
+	// id of the heading, i.e. the link is #ID
+	ID       string
+	Level    int
+	Children TOC
+}
+
+// TOC is a table of contents nested by the level of the headings.
+type TOC []TOCEntry
+
+// HTML renders the table of contents as nested lists of links.
+func (toc TOC) HTML() template.HTML {
+	var buf strings.Builder
+	toc.write(&buf)
+	return template.HTML(buf.String())
+}
+
+// write writes the table of contents as nested lists of links.
+func (toc TOC) write(buf *strings.Builder) {
+	if len(toc) == 0 {
+		return
+	}
+	buf.WriteString("<ul>")
+	for _, entry := range toc {
+		fmt.Fprintf(buf, `<li><a href="#%s">%s</a>`, entry.ID, html.EscapeString(entry.Title))
+		entry.Children.write(buf)
+		buf.WriteString("</li>")
+	}
+	buf.WriteString("</ul>")
+}
+
+// add adds a heading as the last entry of the table of contents, or as a
+// subheading of the last entry if its level is higher.
+func (toc TOC) add(entry TOCEntry) TOC {
+	if n := len(toc); n > 0 && toc[n-1].Level < entry.Level {
+		toc[n-1].Children = toc[n-1].Children.add(entry)
+		return toc
+	}
+	return append(toc, entry)
+}
+
+// slugify returns the id of a heading as github does, i.e. lower case letters,
+// digits, - and _ with the spaces replaced by -.
+func slugify(title string) string {
+	var slug strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(title)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			slug.WriteRune(r)
+		case unicode.IsSpace(r):
+			slug.WriteRune('-')
+		}
+	}
+	if slug.Len() == 0 {
+		return "section"
+	}
+	return slug.String()
+}
+
+// headingText returns the text of the inline tokens of a heading.
+func headingText(tokens []markdown.Token) string {
+	var text strings.Builder
+	for _, token := range tokens {
+		switch token := token.(type) {
+		case *markdown.Text:
+			text.WriteString(token.Content)
+		case *markdown.CodeInline:
+			text.WriteString(token.Content)
+		case *markdown.Image:
+			text.WriteString(headingText(token.Tokens))
+		case *markdown.Softbreak, *markdown.Hardbreak:
+			text.WriteByte(' ')
+		}
+	}
+	return text.String()
+}
+
+// headingAnchors adds unique ids and anchor links to the headings of one or
+// more parsed documents, and collects their table of contents.
+type headingAnchors struct {
+	// max level of the headings in the table of contents
+	depth int
+	used  map[string]bool
+	toc   TOC
+}
+
+// newHeadingAnchors creates a headingAnchors with a table of contents up to
+// the level of the depth.
+func newHeadingAnchors(depth int) *headingAnchors {
+	return &headingAnchors{depth: depth, used: map[string]bool{}}
+}
+
+// add adds unique ids and anchor links to the headings of the parsed tokens,
+// and adds the headings to the table of contents.
+func (a *headingAnchors) add(tokens []markdown.Token) {
+	for i, token := range tokens {
+		heading, ok := token.(*markdown.HeadingOpen)
+		if !ok || i+1 >= len(tokens) {
+			continue
+		}
+		inline, ok := tokens[i+1].(*markdown.Inline)
+		if !ok {
+			continue
+		}
+
+		title := strings.TrimSpace(headingText(inline.Children))
+		slug := slugify(title)
+		id := slug
+		for n := 1; a.used[id]; n++ {
+			id = fmt.Sprintf("%s-%d", slug, n)
+		}
+		a.used[id] = true
+
+		// the renderer does not support attributes on headings
+		tokens[i] = &markdown.HTMLBlock{Content: fmt.Sprintf(`<h%d id="%s">`, heading.HLevel, id)}
+		anchor := &markdown.HTMLInline{Content: fmt.Sprintf(`<a class="anchor" href="#%s" aria-hidden="true">%s</a>`, id, anchorIcon)}
+		inline.Children = append([]markdown.Token{anchor}, inline.Children...)
+
+		if heading.HLevel <= a.depth {
+			a.toc = a.toc.add(TOCEntry{Title: title, ID: id, Level: heading.HLevel})
+		}
+	}
+}
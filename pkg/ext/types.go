@@ -29,6 +29,12 @@
 // ServeHandlerDecorator is an alias for core.ServeHandlerDecorator
 type ServeHandlerDecorator = core.ServeHandlerDecorator
 
+// RequestHandler is an alias for core.RequestHandler
+type RequestHandler = core.RequestHandler
+
+// RequestHandlerDecorator is an alias for core.RequestHandlerDecorator
+type RequestHandlerDecorator = core.RequestHandlerDecorator
+
 // Extension is an alias for core.Extension
 type Extension = core.Extension
 
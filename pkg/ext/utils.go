@@ -0,0 +1,44 @@
+package ext
+
+import (
+	"strings"
+
+	glob "github.com/gobwas/glob"
+)
+
+// splitList splits a comma separated string into a list of trimmed and
+// non-empty values.
+func splitList(s string) []string {
+	var values []string
+	for _, value := range strings.Split(s, ",") {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// compileGlobs compiles a comma separated list of path globs (i.e. * does not
+// match /).
+func compileGlobs(s string) ([]glob.Glob, error) {
+	var globs []glob.Glob
+	for _, pattern := range splitList(s) {
+		g, err := glob.Compile(pattern, '/')
+		if err != nil {
+			return nil, err
+		}
+		globs = append(globs, g)
+	}
+	return globs, nil
+}
+
+// matchAny checks whether the value matches any of the globs.
+func matchAny(globs []glob.Glob, value string) bool {
+	for _, g := range globs {
+		if g.Match(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -4,7 +4,11 @@
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/minio/minio-go"
 	"github.com/minio/minio-go/pkg/credentials"
@@ -61,8 +65,9 @@ func NewMinioClient(config Config) (*minio.Client, error) {
 }
 
 // minioObjectInfoToResourceInfo converts a minio ObjectInfo to ResourceInfo.
-func minioObjectInfoToResourceInfo(info minio.ObjectInfo) ResourceInfo {
+func minioObjectInfoToResourceInfo(bucketName string, info minio.ObjectInfo) ResourceInfo {
 	return ResourceInfo{
+		Bucket:       bucketName,
 		Key:          info.Key,
 		Size:         info.Size,
 		ETag:         info.ETag,
@@ -103,6 +108,17 @@ func (h *Helper) GetBucketNameAndPrefix(url string) (bucketname string, prefix s
 	return h.BucketName, url
 }
 
+// GetURL infers the url of an object, i.e. the inverse of
+// GetBucketNameAndPrefix.
+func (h *Helper) GetURL(bucketName string, key string) string {
+	// remove user provided prefix if any
+	key = strings.TrimPrefix(key, h.Prefix)
+	if h.BucketName == "" {
+		return "/" + bucketName + "/" + key
+	}
+	return "/" + key
+}
+
 // GetObject retrieves the metadata and data from the S3 compatible backend.
 func (h *Helper) GetObject(url string) (Resource, error) {
 	bucketName, prefix := h.GetBucketNameAndPrefix(url)
@@ -123,7 +139,7 @@ func (h *Helper) GetObject(url string) (Resource, error) {
 	}
 	return Resource{
 		Data: obj,
-		Info: minioObjectInfoToResourceInfo(info),
+		Info: minioObjectInfoToResourceInfo(bucketName, info),
 		Msg:  fmt.Sprintf("GET[%s] -> GetObject[%s/%s] ok", url, bucketName, prefix)}, nil
 }
 
@@ -142,6 +158,55 @@ func (h *Helper) StatObject(url string) (Resource, error) {
 	}
 	return Resource{
 		Data: io.Reader(nil),
-		Info: minioObjectInfoToResourceInfo(info),
+		Info: minioObjectInfoToResourceInfo(bucketName, info),
 		Msg:  fmt.Sprintf("StatObject[%s/%s] ok", bucketName, prefix)}, nil
 }
+
+// GetBucketCors retrieves the raw CORS configuration (XML) of a bucket. An
+// empty configuration is returned if the bucket does not have one.
+func (h *Helper) GetBucketCors(bucketName string) ([]byte, error) {
+	// minio-go does not provide an api for bucket cors, hence a presigned
+	// url is used to query the bucket subresource.
+	u, err := h.Client.Presign("GET", bucketName, "", 1*time.Minute, url.Values{"cors": []string{""}})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return []byte{}, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("GetBucketCors[%s]: %s", bucketName, resp.Status)
+	}
+	return data, nil
+}
+
+// PresignedGetObject returns a presigned url to retrieve an object without
+// credentials.
+func (h *Helper) PresignedGetObject(bucketName string, key string, expires time.Duration) (string, error) {
+	u, err := h.Client.PresignedGetObject(bucketName, key, expires, url.Values{})
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// ListObjectsPage lists a page (at most maxKeys objects and folders) of the
+// objects with the prefix. The next page is retrieved with the
+// NextContinuationToken of the result.
+func (h *Helper) ListObjectsPage(bucketName string, prefix string, token string, recursive bool, maxKeys int) (minio.ListBucketV2Result, error) {
+	delimiter := "/"
+	if recursive {
+		delimiter = ""
+	}
+	core := minio.Core{Client: h.Client}
+	return core.ListObjectsV2(bucketName, prefix, token, false, delimiter, maxKeys, "")
+}